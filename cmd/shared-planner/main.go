@@ -3,21 +3,38 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	_ "time/tzdata"
 
 	"github.com/SergeyKozhin/shared-planner-backend/internal/api"
 	events_service "github.com/SergeyKozhin/shared-planner-backend/internal/business/events"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
 	_ "github.com/SergeyKozhin/shared-planner-backend/internal/config"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/changes"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/devices"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/digests"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database/events"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/feeds"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database/group"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/inbox"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/invites"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/outbox"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/passwords"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database/reminders"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database/user"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/notifications"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/fcm"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/jwt"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/mail"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/sms"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/token_parser"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/webhook"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/redis"
 	"github.com/xlab/closer"
 	"go.uber.org/zap"
@@ -44,16 +61,30 @@ func main() {
 	}
 	usersRepository := user.NewRepository()
 	groupsRepository := group.NewRepository()
+	invitesRepository := invites.NewRepository()
 	eventsRepository := events.NewRepository()
-
-	eventsService := events_service.NewService(db, eventsRepository)
-
-	fcmService, err := fcm.NewService(ctx)
+	changesRepository := changes.NewRepository()
+	feedsRepository := feeds.NewRepository()
+	passwordsRepository := passwords.NewRepository()
+	remindersRepository := reminders.NewRepository()
+	outboxRepository := outbox.NewRepository()
+	devicesRepository := devices.NewRepository()
+	inboxRepository := inbox.NewRepository()
+	digestsRepository := digests.NewRepository()
+
+	eventsService := events_service.NewService(db, eventsRepository, remindersRepository, outboxRepository)
+
+	channels, err := initChannels(ctx)
 	if err != nil {
-		log.Fatalf("unable to initializae fcm service: %v", err)
+		log.Fatalf("unable to initializae notification channels: %v", err)
+	}
+
+	channelTypes := make([]model.Channel, len(channels))
+	for i, c := range channels {
+		channelTypes[i] = c.Type()
 	}
 
-	sender := notifications.NewSender(db, logger, groupsRepository, usersRepository, eventsService, fcmService)
+	sender := notifications.NewSender(db, logger, groupsRepository, usersRepository, remindersRepository, outboxRepository, devicesRepository, inboxRepository, digestsRepository, changesRepository, eventsService, channels)
 	go sender.Start(ctx)
 
 	api, err := api.NewApi(
@@ -65,7 +96,18 @@ func main() {
 		db,
 		usersRepository,
 		groupsRepository,
+		invitesRepository,
+		changesRepository,
+		feedsRepository,
+		passwordsRepository,
+		remindersRepository,
+		devicesRepository,
+		outboxRepository,
+		inboxRepository,
+		digestsRepository,
 		eventsService,
+		sender,
+		channelTypes,
 	)
 
 	errLogger, err := zap.NewStdLogAt(logger.Desugar(), zap.ErrorLevel)
@@ -83,6 +125,61 @@ func main() {
 	logger.Fatalw("server error", "err", server.ListenAndServe())
 }
 
+// initChannels creates channels that are configured, push is written to the log instead of FCM
+// with PUSH_PROVIDER=log, so that the server can run without Firebase credentials.
+func initChannels(ctx context.Context) ([]notifications.Channel, error) {
+	var channels []notifications.Channel
+
+	switch config.PushProvider() {
+	case "fcm":
+		fcmService, err := fcm.NewService(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("init fcm service: %w", err)
+		}
+		channels = append(channels, notifications.NewFCMChannel(fcmService))
+	case "log":
+		w := io.Writer(os.Stdout)
+		if config.NotifyLogPath() != "" {
+			f, err := os.OpenFile(config.NotifyLogPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("open notifications log: %w", err)
+			}
+			closer.Bind(func() {
+				_ = f.Close()
+			})
+			w = f
+		}
+		channels = append(channels, notifications.NewLogChannel(model.ChannelPush, w))
+	default:
+		return nil, fmt.Errorf("unknown push provider %q", config.PushProvider())
+	}
+
+	if config.SMTPHost() != "" {
+		channels = append(channels, notifications.NewEmailChannel(mail.NewService(
+			config.SMTPHost(),
+			config.SMTPPort(),
+			config.SMTPUsername(),
+			config.SMTPPassword(),
+			config.SMTPFrom(),
+		)))
+	}
+
+	if config.WebhooksEnabled() {
+		channels = append(channels, notifications.NewWebhookChannel(webhook.NewClient(config.WebhookSecret(), config.ChannelTimeout())))
+	}
+
+	if config.SMSGatewayURL() != "" {
+		channels = append(channels, notifications.NewSMSChannel(sms.NewClient(
+			config.SMSGatewayURL(),
+			config.SMSGatewayToken(),
+			config.SMSFrom(),
+			config.ChannelTimeout(),
+		)))
+	}
+
+	return channels, nil
+}
+
 func initLogger() (*zap.SugaredLogger, error) {
 	var logger *zap.Logger
 	var err error
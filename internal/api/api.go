@@ -26,7 +26,22 @@ type Api struct {
 	db            database.PGX
 	users         userRepository
 	groups        groupsRepository
+	invites       invitesRepository
+	changes       changesRepository
+	feeds         feedsRepository
+	passwords     passwordsRepository
+	reminders     remindersRepository
+	devices       devicesRepository
+	outbox        outboxRepository
+	inbox         inboxRepository
+	digests       digestsRepository
 	eventsService eventsService
+
+	// reminderActions handle snooze and done actions of sent reminders
+	reminderActions reminderActions
+
+	// channels are notification channels configured on the server
+	channels []model.Channel
 }
 
 type jwtManager interface {
@@ -53,29 +68,108 @@ type userRepository interface {
 	GetUserByID(ctx context.Context, q database.Queryable, id int64) (*model.User, error)
 	GetUsersByIDs(ctx context.Context, q database.Queryable, ids []int64) ([]*model.User, error)
 	SearchUsers(ctx context.Context, q database.Queryable, filter model.UserSearchFilter) ([]*model.User, error)
-	UpdateUserPushToken(ctx context.Context, q database.Queryable, id int64, token string) error
 	UpdateNotify(ctx context.Context, q database.Queryable, id int64, notify bool) error
+	UpdateTimeZone(ctx context.Context, q database.Queryable, id int64, timeZone string) error
+	UpdateChannels(ctx context.Context, q database.Queryable, id int64, channels []model.Channel, webhookURL string) error
+	UpdateQuietHours(ctx context.Context, q database.Queryable, id int64, quiet *model.QuietHours) error
+	UpdateSnoozeUntil(ctx context.Context, q database.Queryable, id int64, until time.Time) error
 }
 
 type groupsRepository interface {
 	GetGroup(ctx context.Context, q database.Queryable, id int64) (*model.Group, error)
+	LockGroup(ctx context.Context, q database.Queryable, id int64) (int64, error)
+	GetGroups(ctx context.Context, q database.Queryable, ids []int64) ([]*model.Group, error)
 	GetUserGroups(ctx context.Context, q database.Queryable, userID int64) ([]*model.Group, error)
 	GetUserGroupSettings(ctx context.Context, q database.Queryable, filter model.UserGroupSettingsFilter) ([]*model.GroupSettings, error)
 	CreateGroup(ctx context.Context, q database.Queryable, group *model.GroupCreate) (int64, error)
 	AddUserToGroup(ctx context.Context, q database.Queryable, settings *model.GroupSettings) error
 	RemoveUserFromGroup(ctx context.Context, q database.Queryable, groupID int64, userID int64) error
+	UpdateUserRole(ctx context.Context, q database.Queryable, groupID int64, userID int64, role model.GroupRole) error
+	IncrementGroupVersion(ctx context.Context, q database.Queryable, groupID int64) error
 	UpdateGroupName(ctx context.Context, q database.Queryable, groupID int64, name string) error
 	UpdateGroupSettings(ctx context.Context, q database.Queryable, settings *model.GroupSettings) error
 }
 
+type invitesRepository interface {
+	CreateInvite(ctx context.Context, q database.Queryable, invite *model.GroupInvite) (int64, error)
+	GetInviteByCodeHash(ctx context.Context, q database.Queryable, hash string) (*model.GroupInvite, error)
+	GetInvites(ctx context.Context, q database.Queryable, filter model.InvitesFilter) ([]*model.GroupInvite, error)
+	TakeInvite(ctx context.Context, q database.Queryable, id int64, groupID int64, userID int64) (*model.GroupInvite, error)
+	DeleteUserInvites(ctx context.Context, q database.Queryable, groupID int64, userID int64) error
+	DeleteExpiredInvites(ctx context.Context, q database.Queryable, groupID int64) error
+}
+
+type changesRepository interface {
+	GetSyncPoint(ctx context.Context, q database.Queryable) (int64, error)
+	GetHorizon(ctx context.Context, q database.Queryable) (int64, error)
+	GetChanges(ctx context.Context, q database.Queryable, filter model.ChangesFilter) ([]*model.Change, error)
+}
+
+type feedsRepository interface {
+	GetFeedByToken(ctx context.Context, q database.Queryable, token string) (*model.Feed, error)
+	GetUserFeeds(ctx context.Context, q database.Queryable, userID int64) ([]*model.Feed, error)
+	UpsertFeed(ctx context.Context, q database.Queryable, feed *model.Feed) error
+	DeleteFeed(ctx context.Context, q database.Queryable, userID int64, groupID int64) error
+}
+
+type passwordsRepository interface {
+	CreateAppPassword(ctx context.Context, q database.Queryable, password *model.AppPassword) (*model.AppPassword, error)
+	GetAppPasswordByHash(ctx context.Context, q database.Queryable, hash string) (*model.AppPassword, error)
+	GetUserAppPasswords(ctx context.Context, q database.Queryable, userID int64) ([]*model.AppPassword, error)
+	TouchAppPassword(ctx context.Context, q database.Queryable, id int64) error
+	DeleteAppPassword(ctx context.Context, q database.Queryable, userID int64, id int64) error
+}
+
+type remindersRepository interface {
+	GetReminders(ctx context.Context, q database.Queryable, filter model.RemindersFilter) ([]*model.Reminders, error)
+	UpsertReminders(ctx context.Context, q database.Queryable, reminders *model.Reminders) error
+	DeleteReminders(ctx context.Context, q database.Queryable, userID int64, eventID int64) error
+}
+
+type devicesRepository interface {
+	GetUsersDevices(ctx context.Context, q database.Queryable, userIDs []int64) ([]*model.Device, error)
+	UpsertDevice(ctx context.Context, q database.Queryable, device *model.Device) error
+	MoveDevice(ctx context.Context, q database.Queryable, oldSessionHash string, newSessionHash string) error
+	DeleteDevice(ctx context.Context, q database.Queryable, sessionHash string) error
+	UpsertLegacyDevice(ctx context.Context, q database.Queryable, userID int64, pushToken string) error
+	DeleteLegacyDevices(ctx context.Context, q database.Queryable, userID int64) error
+}
+
+type outboxRepository interface {
+	AddChangeNotifications(ctx context.Context, q database.Queryable, change *model.GroupChange, sendAt time.Time, maxDelay time.Duration) error
+}
+
+type inboxRepository interface {
+	GetInboxItems(ctx context.Context, q database.Queryable, filter model.InboxFilter) ([]*model.InboxItem, error)
+	CountUnread(ctx context.Context, q database.Queryable, userID int64) (int64, error)
+	MarkRead(ctx context.Context, q database.Queryable, userID int64, ids []int64) error
+	MarkAllRead(ctx context.Context, q database.Queryable, userID int64) error
+}
+
+type digestsRepository interface {
+	GetDigestSettings(ctx context.Context, q database.Queryable, filter model.DigestSettingsFilter) ([]*model.DigestSettings, error)
+	UpsertDigestSettings(ctx context.Context, q database.Queryable, settings *model.DigestSettings) error
+}
+
+type reminderActions interface {
+	SnoozeReminder(ctx context.Context, userID int64, id int64, d time.Duration) (time.Time, error)
+	AckReminder(ctx context.Context, userID int64, id int64) error
+}
+
 type eventsService interface {
-	CreateEvent(ctx context.Context, info *model.EventCreate) (*model.Event, error)
+	CreateEvent(ctx context.Context, userID int64, info *model.EventCreate) (*model.Event, error)
 	GetEvents(ctx context.Context, filter model.EventsFilter) ([]*model.Event, error)
 	GetEventByID(ctx context.Context, id int64, ts time.Time) (*model.Event, error)
-	UpdateEvent(ctx context.Context, id int64, ts time.Time, info *model.EventUpdate) error
-	UpdateEventInstance(ctx context.Context, id int64, ts time.Time, info *model.EventUpdate) error
-	DeleteEvent(ctx context.Context, id int64) error
-	DeleteEventInstance(ctx context.Context, id int64, ts time.Time) error
+	GetSeries(ctx context.Context, filter model.EventsFilter) ([]*model.Series, error)
+	UpdateEvent(ctx context.Context, userID int64, id int64, version int64, ts time.Time, info *model.EventUpdate) error
+	UpdateEventInstance(ctx context.Context, userID int64, id int64, version int64, ts time.Time, info *model.EventUpdate) error
+	UpdateEventFollowing(ctx context.Context, userID int64, id int64, version int64, ts time.Time, info *model.EventUpdate) error
+	DeleteEvent(ctx context.Context, userID int64, id int64, version int64) error
+	DeleteEventInstance(ctx context.Context, userID int64, id int64, version int64, ts time.Time) error
+	DeleteEventFollowing(ctx context.Context, userID int64, id int64, version int64, ts time.Time) error
+	ImportEvents(ctx context.Context, userID int64, groupID int64, events []*model.ImportedEvent) ([]*model.ImportItem, error)
+	ReplaceSeries(ctx context.Context, userID int64, id int64, version int64, info *model.ImportedEvent) error
+	RefreshSchedules(ctx context.Context, ids []int64, after time.Time) error
 }
 
 func NewApi(
@@ -87,18 +181,40 @@ func NewApi(
 	db database.PGX,
 	users userRepository,
 	groups groupsRepository,
+	invites invitesRepository,
+	changes changesRepository,
+	feeds feedsRepository,
+	passwords passwordsRepository,
+	reminders remindersRepository,
+	devices devicesRepository,
+	outbox outboxRepository,
+	inbox inboxRepository,
+	digests digestsRepository,
 	eventsService eventsService,
+	reminderActions reminderActions,
+	channels []model.Channel,
 ) (*Api, error) {
 	a := &Api{
-		logger:        logger,
-		randSource:    randSource,
-		jwts:          jwts,
-		tokenParser:   tokenParser,
-		refreshTokens: refreshTokens,
-		db:            db,
-		users:         users,
-		groups:        groups,
-		eventsService: eventsService,
+		logger:          logger,
+		randSource:      randSource,
+		jwts:            jwts,
+		tokenParser:     tokenParser,
+		refreshTokens:   refreshTokens,
+		db:              db,
+		users:           users,
+		groups:          groups,
+		invites:         invites,
+		changes:         changes,
+		feeds:           feeds,
+		passwords:       passwords,
+		reminders:       reminders,
+		devices:         devices,
+		outbox:          outbox,
+		inbox:           inbox,
+		digests:         digests,
+		eventsService:   eventsService,
+		reminderActions: reminderActions,
+		channels:        channels,
 	}
 	a.setupHandler()
 
@@ -117,6 +233,10 @@ func (a *Api) setupHandler() {
 		})
 	}
 
+	for _, m := range davMethods {
+		chi.RegisterMethod(m)
+	}
+
 	r := chi.NewMux()
 
 	r.Use(middleware.Logger, middleware.Recoverer, middleware.StripSlashes)
@@ -135,34 +255,90 @@ func (a *Api) setupHandler() {
 
 	r.Post("/files", a.uploadFileHandler)
 
+	r.Get("/feeds/{token}.ics", a.feedHandler)
+
+	r.With(a.davAuth).HandleFunc("/.well-known/caldav", a.davHandler)
+	r.With(a.davAuth).HandleFunc(davPrefix+"/*", a.davHandler)
+
 	r.With(a.auth).Route("/", func(r chi.Router) {
 		r.With(a.userCtx).Route("/user", func(r chi.Router) {
 			r.Get("/", a.getUserHandler)
 			r.Put("/push_token", a.updateUserPushTokenHandler)
+			r.Get("/devices", a.getDevicesHandler)
+			r.Put("/device", a.registerDeviceHandler)
+			r.Delete("/device", a.unregisterDeviceHandler)
 			r.Put("/notify", a.updateUserNotifyHandler)
+			r.Put("/time_zone", a.updateUserTimeZoneHandler)
+			r.Get("/channels", a.getUserChannelsHandler)
+			r.Put("/channels", a.updateUserChannelsHandler)
+			r.Get("/digest", a.getDigestSettingsHandler)
+			r.Put("/digest", a.updateDigestSettingsHandler)
+			r.Get("/quiet_hours", a.getQuietHoursHandler)
+			r.Put("/quiet_hours", a.updateQuietHoursHandler)
+			r.Put("/snooze", a.updateSnoozeHandler)
+			r.Get("/feeds", a.getFeedsHandler)
+			r.Post("/feed", a.rotateUserFeedHandler)
+			r.Delete("/feed", a.revokeUserFeedHandler)
+			r.Get("/app_passwords", a.getAppPasswordsHandler)
+			r.Post("/app_passwords", a.createAppPasswordHandler)
+			r.Delete("/app_passwords/{passwordID}", a.deleteAppPasswordHandler)
+			r.Get("/invites", a.getUserInvitesHandler)
 		})
 
 		r.Get("/users", a.searchUsersHandler)
 
+		r.Route("/reminders/{reminderID}", func(r chi.Router) {
+			r.Post("/snooze", a.snoozeReminderHandler)
+			r.Post("/done", a.ackReminderHandler)
+		})
+
+		r.Route("/inbox", func(r chi.Router) {
+			r.Get("/", a.getInboxHandler)
+			r.Get("/unread_count", a.getUnreadCountHandler)
+			r.Post("/read", a.markInboxReadHandler)
+			r.Post("/read_all", a.markInboxAllReadHandler)
+		})
+
+		r.Route("/invites", func(r chi.Router) {
+			r.Post("/join", a.joinGroupHandler)
+			r.Post("/{inviteID}/accept", a.acceptInviteHandler)
+			r.Post("/{inviteID}/decline", a.declineInviteHandler)
+		})
+
 		r.Route("/groups", func(r chi.Router) {
 			r.Get("/", a.getUserGroupsHandler)
 			r.Post("/", a.createGroupHandler)
 			r.With(a.groupCtx).Route("/{groupID}", func(r chi.Router) {
 				r.Get("/", a.getGroupHandler)
-				r.Put("/", a.updateGroupHandler)
+				r.With(a.requireRole(managerRoles)).Put("/", a.updateGroupHandler)
+				r.With(a.requireRole(managerRoles)).Put("/members", a.updateMembersHandler)
+				r.With(a.requireRole(managerRoles)).Route("/invites", func(r chi.Router) {
+					r.Get("/", a.getGroupInvitesHandler)
+					r.Post("/", a.createGroupInviteHandler)
+					r.Post("/link", a.createInviteLinkHandler)
+					r.Delete("/{inviteID}", a.cancelGroupInviteHandler)
+				})
 				r.Put("/settings", a.updateGroupSettingsHandler)
+				r.Post("/feed", a.rotateGroupFeedHandler)
+				r.Delete("/feed", a.revokeGroupFeedHandler)
 			})
 		})
 
 		r.With(a.userGroupsCtx).Route("/events", func(r chi.Router) {
 			r.Get("/", a.getEventsHandler)
 			r.Post("/", a.createEventHandler)
+			r.Post("/import", a.importEventsHandler)
 			r.With(a.eventCtx).Route("/{eventID}", func(r chi.Router) {
 				r.Get("/", a.getEventHandler)
-				r.Put("/", a.updateEventHandler)
-				r.Delete("/", a.deleteEventHandler)
+				r.With(a.requireRole(editorRoles)).Put("/", a.updateEventHandler)
+				r.With(a.requireRole(editorRoles)).Delete("/", a.deleteEventHandler)
+				r.Get("/reminders", a.getRemindersHandler)
+				r.Put("/reminders", a.updateRemindersHandler)
+				r.Delete("/reminders", a.resetRemindersHandler)
 			})
 		})
+
+		r.Get("/sync", a.syncHandler)
 	})
 
 	fileServer := http.FileServer(http.Dir("./files"))
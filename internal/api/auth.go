@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
@@ -118,6 +119,11 @@ func (a *Api) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 		break
 	}
 
+	if err := a.devices.MoveDevice(r.Context(), a.db, hashToken(input.RefreshToken), hashToken(newRefreshToken)); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("move device: %w", err))
+		return
+	}
+
 	response := &struct {
 		AccessToken  string `json:"access_token"`
 		RefreshToken string `json:"refresh_token"`
@@ -141,6 +147,17 @@ func (a *Api) logoutUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	id, err := a.refreshTokens.Get(r.Context(), input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.unauthorizedResponse(w, r, errors.New("no such session"))
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
 	if err := a.refreshTokens.Delete(r.Context(), input.RefreshToken); err != nil {
 		switch {
 		case errors.Is(err, model.ErrNoRecord):
@@ -151,5 +168,16 @@ func (a *Api) logoutUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := a.devices.DeleteDevice(r.Context(), a.db, hashToken(input.RefreshToken)); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete device: %w", err))
+		return
+	}
+
+	// the legacy device can't be told apart by the session, so it stops receiving notifications on any logout
+	if err := a.devices.DeleteLegacyDevices(r.Context(), a.db, id); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete legacy devices: %w", err))
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
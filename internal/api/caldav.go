@@ -0,0 +1,461 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/icalendar"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+)
+
+const (
+	davPrefix        = "/dav"
+	davPrincipalPath = davPrefix + "/user/"
+	davCalendarsPath = davPrincipalPath + "calendars/"
+)
+
+var davMethods = []string{"PROPFIND", "PROPPATCH", "REPORT", "MKCOL", "MKCALENDAR", "COPY", "MOVE"}
+
+var (
+	errCalendarNotFound = errors.New("calendar not found")
+	errCalendarReadOnly = errors.New("calendar is read-only for viewers")
+)
+
+// davHandler serves CalDAV, groups of the user are calendar collections and each series
+// with its overrides is a calendar object named by its UID.
+func (a *Api) davHandler(w http.ResponseWriter, r *http.Request) {
+	h := &caldav.Handler{
+		Backend: &calDAVBackend{
+			a:       a,
+			baseURL: publicURL(r),
+		},
+		Prefix: davPrefix,
+	}
+
+	// the backend gets If-Match only for PUT, DELETE reads it from the context
+	ifMatch := webdav.ConditionalMatch(r.Header.Get("If-Match"))
+	h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyIfMatch, ifMatch)))
+}
+
+type calDAVBackend struct {
+	a       *Api
+	baseURL string
+}
+
+func (b *calDAVBackend) CurrentUserPrincipal(context.Context) (string, error) {
+	return davPrincipalPath, nil
+}
+
+func (b *calDAVBackend) CalendarHomeSetPath(context.Context) (string, error) {
+	return davCalendarsPath, nil
+}
+
+func (b *calDAVBackend) CreateCalendar(context.Context, *caldav.Calendar) error {
+	return webdav.NewHTTPError(http.StatusForbidden, errors.New("calendars are created as groups in the app"))
+}
+
+func (b *calDAVBackend) ListCalendars(ctx context.Context) ([]caldav.Calendar, error) {
+	userID, ok := ctx.Value(contextKeyID).(int64)
+	if !ok {
+		return nil, b.serverError(errCantRetrieveID)
+	}
+
+	groups, err := b.a.groups.GetUserGroups(ctx, b.a.db, userID)
+	if err != nil {
+		return nil, b.serverError(fmt.Errorf("get groups: %w", err))
+	}
+
+	res := make([]caldav.Calendar, len(groups))
+	for i, g := range groups {
+		res[i] = newDAVCalendar(g)
+	}
+
+	return res, nil
+}
+
+func (b *calDAVBackend) GetCalendar(ctx context.Context, path string) (*caldav.Calendar, error) {
+	group, _, err := b.resolvePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := newDAVCalendar(group)
+	return &cal, nil
+}
+
+func (b *calDAVBackend) GetCalendarObject(ctx context.Context, path string, _ *caldav.CalendarCompRequest) (*caldav.CalendarObject, error) {
+	group, uid, err := b.resolvePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := b.findSeries(ctx, group.ID, uid)
+	if err != nil {
+		return nil, b.error(err)
+	}
+
+	return b.newObject(series)
+}
+
+func (b *calDAVBackend) ListCalendarObjects(ctx context.Context, path string, _ *caldav.CalendarCompRequest) ([]caldav.CalendarObject, error) {
+	group, _, err := b.resolvePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.listObjects(ctx, model.EventsFilter{GroupIDs: []int64{group.ID}})
+}
+
+// QueryCalendarObjects supports time range of events, other filters are ignored.
+func (b *calDAVBackend) QueryCalendarObjects(ctx context.Context, path string, query *caldav.CalendarQuery) ([]caldav.CalendarObject, error) {
+	group, _, err := b.resolvePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.CompFilter.Name != ical.CompCalendar {
+		return nil, nil
+	}
+
+	filter := model.EventsFilter{GroupIDs: []int64{group.ID}}
+
+	if len(query.CompFilter.Comps) != 0 {
+		var eventFilter *caldav.CompFilter
+		for i, c := range query.CompFilter.Comps {
+			if c.Name == ical.CompEvent {
+				eventFilter = &query.CompFilter.Comps[i]
+			}
+		}
+		if eventFilter == nil {
+			return nil, nil
+		}
+
+		// open ranges would require expanding series forever, all of them are returned instead
+		if !eventFilter.Start.IsZero() && !eventFilter.End.IsZero() {
+			instances, err := b.a.eventsService.GetEvents(ctx, model.EventsFilter{
+				From:     eventFilter.Start,
+				To:       eventFilter.End,
+				GroupIDs: []int64{group.ID},
+			})
+			if err != nil {
+				return nil, b.serverError(fmt.Errorf("get events: %w", err))
+			}
+
+			ids := newIDSet()
+			for _, e := range instances {
+				id, _, err := splitID(e.ID)
+				if err != nil {
+					return nil, b.serverError(err)
+				}
+				ids.add(id)
+			}
+
+			if len(ids.ids) == 0 {
+				return nil, nil
+			}
+			filter.IDs = ids.ids
+		}
+	}
+
+	return b.listObjects(ctx, filter)
+}
+
+// PutCalendarObject creates the series or replaces the existing one with the same UID.
+// Objects are named by UID, so the name in the path must match UID of the object.
+func (b *calDAVBackend) PutCalendarObject(ctx context.Context, path string, cal *ical.Calendar, opts *caldav.PutCalendarObjectOptions) (*caldav.CalendarObject, error) {
+	userID, ok := ctx.Value(contextKeyID).(int64)
+	if !ok {
+		return nil, b.serverError(errCantRetrieveID)
+	}
+
+	group, pathUID, err := b.resolvePath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasRole(group.Roles[userID], editorRoles) {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errCalendarReadOnly)
+	}
+
+	compType, uid, err := caldav.ValidateCalendarObject(cal)
+	if err != nil {
+		return nil, webdav.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if compType != ical.CompEvent {
+		return nil, caldav.NewPreconditionError(caldav.PreconditionSupportedCalendarComponent)
+	}
+	if uid != pathUID {
+		return nil, caldav.NewPreconditionError(caldav.PreconditionNoUIDConflict)
+	}
+
+	user, err := b.a.users.GetUserByID(ctx, b.a.db, userID)
+	if err != nil {
+		return nil, b.serverError(fmt.Errorf("get user: %w", err))
+	}
+
+	loc, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	events, items, err := icalendar.DecodeCalendars([]*ical.Calendar{cal}, icalendar.DecodeOptions{
+		Location:          loc,
+		SupportedReminder: validNotifyOffset,
+	})
+	if err != nil {
+		return nil, webdav.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if len(events) != 1 {
+		reason := "event can't be stored"
+		if len(items) != 0 {
+			reason = items[0].Reason
+		}
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New(reason))
+	}
+
+	existing, err := b.findSeries(ctx, group.ID, uid)
+	if err != nil && !errors.Is(err, model.ErrNoRecord) {
+		return nil, b.error(err)
+	}
+
+	if existing == nil {
+		if opts.IfMatch.IsSet() {
+			return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("event does not exist"))
+		}
+
+		report, err := b.a.eventsService.ImportEvents(ctx, userID, group.ID, events)
+		if err != nil {
+			return nil, b.serverError(fmt.Errorf("import events: %w", err))
+		}
+
+		switch report[0].Status {
+		case model.ImportStatusSkipped:
+			return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New(report[0].Reason))
+		case model.ImportStatusUnsupported:
+			return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New(report[0].Reason))
+		}
+	} else {
+		if opts.IfNoneMatch.IsWildcard() {
+			return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("event already exists"))
+		}
+
+		version, err := ifMatchVersion(opts.IfMatch)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := strconv.ParseInt(existing.Event.ID, 10, 64)
+		if err != nil {
+			return nil, b.serverError(fmt.Errorf("parse event id %q: %w", existing.Event.ID, err))
+		}
+
+		if err := b.a.eventsService.ReplaceSeries(ctx, userID, id, version, events[0]); err != nil {
+			return nil, b.error(err)
+		}
+	}
+
+	series, err := b.findSeries(ctx, group.ID, uid)
+	if err != nil {
+		return nil, b.error(err)
+	}
+
+	return b.newObject(series)
+}
+
+func (b *calDAVBackend) DeleteCalendarObject(ctx context.Context, path string) error {
+	userID, ok := ctx.Value(contextKeyID).(int64)
+	if !ok {
+		return b.serverError(errCantRetrieveID)
+	}
+
+	group, uid, err := b.resolvePath(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if !hasRole(group.Roles[userID], editorRoles) {
+		return webdav.NewHTTPError(http.StatusForbidden, errCalendarReadOnly)
+	}
+
+	ifMatch, _ := ctx.Value(contextKeyIfMatch).(webdav.ConditionalMatch)
+
+	series, err := b.findSeries(ctx, group.ID, uid)
+	if errors.Is(err, model.ErrNoRecord) && ifMatch.IsSet() {
+		return webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("event does not exist"))
+	}
+	if err != nil {
+		return b.error(err)
+	}
+
+	version, err := ifMatchVersion(ifMatch)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(series.Event.ID, 10, 64)
+	if err != nil {
+		return b.serverError(fmt.Errorf("parse event id %q: %w", series.Event.ID, err))
+	}
+
+	if err := b.a.eventsService.DeleteEvent(ctx, userID, id, version); err != nil {
+		return b.error(err)
+	}
+
+	return nil
+}
+
+// ifMatchVersion returns the version from the ETag of If-Match,
+// DAV clients may change the object without it, so model.AnyVersion is returned then.
+func ifMatchVersion(ifMatch webdav.ConditionalMatch) (int64, error) {
+	if !ifMatch.IsSet() || ifMatch.IsWildcard() {
+		return model.AnyVersion, nil
+	}
+
+	etag, err := ifMatch.ETag()
+	if err != nil {
+		return 0, webdav.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	version, err := strconv.ParseInt(etag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, webdav.NewHTTPError(http.StatusPreconditionFailed, model.ErrEditConflict)
+	}
+
+	return version, nil
+}
+
+// resolvePath returns the group of the calendar or object path, if the user is its member,
+// and UID of the object if the path points to it.
+func (b *calDAVBackend) resolvePath(ctx context.Context, path string) (*model.Group, string, error) {
+	userID, ok := ctx.Value(contextKeyID).(int64)
+	if !ok {
+		return nil, "", b.serverError(errCantRetrieveID)
+	}
+
+	if !strings.HasPrefix(path, davCalendarsPath) {
+		return nil, "", webdav.NewHTTPError(http.StatusNotFound, errCalendarNotFound)
+	}
+
+	parts := strings.SplitN(strings.Trim(strings.TrimPrefix(path, davCalendarsPath), "/"), "/", 2)
+
+	groupID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, "", webdav.NewHTTPError(http.StatusNotFound, errCalendarNotFound)
+	}
+
+	var uid string
+	if len(parts) == 2 {
+		uid = strings.TrimSuffix(parts[1], ".ics")
+	}
+
+	groups, err := b.a.groups.GetUserGroups(ctx, b.a.db, userID)
+	if err != nil {
+		return nil, "", b.serverError(fmt.Errorf("get groups: %w", err))
+	}
+
+	for _, g := range groups {
+		if g.ID == groupID {
+			return g, uid, nil
+		}
+	}
+
+	return nil, "", webdav.NewHTTPError(http.StatusNotFound, errCalendarNotFound)
+}
+
+// findSeries looks the series up by UID, events created in the app don't store it.
+func (b *calDAVBackend) findSeries(ctx context.Context, groupID int64, uid string) (*model.Series, error) {
+	filters := []model.EventsFilter{{GroupIDs: []int64{groupID}, UIDs: []string{uid}}}
+	if id, ok := icalendar.ParseUID(uid); ok {
+		filters = append(filters, model.EventsFilter{GroupIDs: []int64{groupID}, IDs: []int64{id}})
+	}
+
+	for _, f := range filters {
+		series, err := b.a.eventsService.GetSeries(ctx, f)
+		if err != nil {
+			return nil, fmt.Errorf("get series: %w", err)
+		}
+
+		for _, s := range series {
+			if icalendar.UID(s.Event) == uid {
+				return s, nil
+			}
+		}
+	}
+
+	return nil, model.ErrNoRecord
+}
+
+func (b *calDAVBackend) listObjects(ctx context.Context, filter model.EventsFilter) ([]caldav.CalendarObject, error) {
+	series, err := b.a.eventsService.GetSeries(ctx, filter)
+	if err != nil {
+		return nil, b.serverError(fmt.Errorf("get series: %w", err))
+	}
+
+	res := make([]caldav.CalendarObject, len(series))
+	for i, s := range series {
+		object, err := b.newObject(s)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = *object
+	}
+
+	return res, nil
+}
+
+// newObject uses version of the series as ETag, it is bumped by changes of the overrides as well.
+func (b *calDAVBackend) newObject(s *model.Series) (*caldav.CalendarObject, error) {
+	cal, err := icalendar.SeriesToCalendar(s, b.baseURL)
+	if err != nil {
+		return nil, b.serverError(fmt.Errorf("encode series %v: %w", s.Event.ID, err))
+	}
+
+	modTime := s.Event.UpdatedAt
+	for _, o := range s.Overrides {
+		if o.UpdatedAt.After(modTime) {
+			modTime = o.UpdatedAt
+		}
+	}
+
+	return &caldav.CalendarObject{
+		Path:    fmt.Sprintf("%s%d/%s.ics", davCalendarsPath, s.Event.GroupID, icalendar.UID(s.Event)),
+		ModTime: modTime,
+		ETag:    strconv.FormatInt(s.Event.Version, 10),
+		Data:    cal,
+	}, nil
+}
+
+func (b *calDAVBackend) error(err error) error {
+	switch {
+	case errors.Is(err, model.ErrNoRecord):
+		return webdav.NewHTTPError(http.StatusNotFound, err)
+	case errors.Is(err, model.ErrEditConflict):
+		return webdav.NewHTTPError(http.StatusPreconditionFailed, err)
+	default:
+		return b.serverError(err)
+	}
+}
+
+// serverError hides the cause from the client, the same way as serverErrorResponse does.
+func (b *calDAVBackend) serverError(err error) error {
+	b.a.logger.Errorw("caldav error", "err", err)
+	return webdav.NewHTTPError(http.StatusInternalServerError, errors.New("the server encountered a problem and could not process your request"))
+}
+
+func newDAVCalendar(g *model.Group) caldav.Calendar {
+	return caldav.Calendar{
+		Path:                  fmt.Sprintf("%s%d/", davCalendarsPath, g.ID),
+		Name:                  g.Name,
+		MaxResourceSize:       config.MaxFileSize(),
+		SupportedComponentSet: []string{ical.CompEvent},
+	}
+}
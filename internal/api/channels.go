@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/webhook"
+)
+
+type channelsResp struct {
+	Channels   []model.Channel `json:"channels"`
+	WebhookURL string          `json:"webhook_url"`
+	// Available lists channels configured on the server.
+	Available []model.Channel `json:"available"`
+}
+
+func (a *Api) getUserChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	resp := &channelsResp{
+		Channels:   user.Channels,
+		WebhookURL: user.WebhookURL,
+		Available:  a.channels,
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateUserChannelsHandler sets channels the user gets notifications by, addresses of email and SMS
+// are taken from the profile.
+func (a *Api) updateUserChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	req := &struct {
+		Channels   []model.Channel `json:"channels"`
+		WebhookURL string          `json:"webhook_url"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	available := make([]string, len(a.channels))
+	for i, c := range a.channels {
+		available[i] = string(c)
+	}
+
+	v := validator.New()
+	seen := make(map[model.Channel]struct{}, len(req.Channels))
+	for _, c := range req.Channels {
+		v.Check(validator.In(string(c), available...), "channels", fmt.Sprintf("channel %q is not available", c))
+
+		_, ok := seen[c]
+		v.Check(!ok, "channels", "channels must be unique")
+		seen[c] = struct{}{}
+	}
+
+	if _, ok := seen[model.ChannelSMS]; ok {
+		v.Check(user.PhoneNumber != "", "channels", "phone number is required for sms")
+	}
+	if _, ok := seen[model.ChannelWebhook]; ok {
+		v.Check(validWebhookURL(req.WebhookURL), "webhook_url", "webhook url must be valid public https url")
+	}
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if req.Channels == nil {
+		req.Channels = []model.Channel{}
+	}
+
+	if err := a.users.UpdateChannels(r.Context(), a.db, user.ID, req.Channels, req.WebhookURL); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("update channels: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// validWebhookURL rejects local hosts early, hosts resolving to them are rejected by the webhook client.
+func validWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return false
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !webhook.PublicIP(ip) {
+		return false
+	}
+
+	return true
+}
@@ -6,7 +6,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
 )
 
 type userResp struct {
@@ -15,6 +17,7 @@ type userResp struct {
 	Email       string `json:"email,omitempty"`
 	PhoneNumber string `json:"phone_number,omitempty"`
 	Photo       string `json:"photo,omitempty"`
+	TimeZone    string `json:"time_zone,omitempty"`
 }
 
 func mapToUserResp(user *model.User) (*userResp, error) {
@@ -24,6 +27,7 @@ func mapToUserResp(user *model.User) (*userResp, error) {
 		Email:       user.Email,
 		PhoneNumber: user.PhoneNumber,
 		Photo:       user.Photo,
+		TimeZone:    user.TimeZone,
 	}, nil
 }
 
@@ -33,24 +37,30 @@ type attachment struct {
 }
 
 type eventResp struct {
-	ID            string           `json:"id"`
-	GroupID       int64            `json:"group_id"`
-	EventType     model.EventType  `json:"event_type"`
-	Title         string           `json:"title"`
-	Description   string           `json:"description"`
-	AllDay        bool             `json:"all_day"`
-	From          dateTime         `json:"from"`
-	To            dateTime         `json:"to"`
-	RepeatType    model.RepeatType `json:"repeat_type"`
-	Notifications []duration       `json:"notifications"`
-	Attachments   []*attachment    `json:"attachments"`
+	ID            string             `json:"id"`
+	GroupID       int64              `json:"group_id"`
+	EventType     model.EventType    `json:"event_type"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	AllDay        bool               `json:"all_day"`
+	From          dateTime           `json:"from"`
+	To            dateTime           `json:"to"`
+	TimeZone      string             `json:"time_zone"`
+	RepeatType    model.RepeatType   `json:"repeat_type"`
+	Recurrence    *recurrence        `json:"recurrence"`
+	RecurrenceID  *dateTime          `json:"recurrence_id"`
+	Notifications []notificationType `json:"notifications"`
+	NotifyOffsets []duration         `json:"notify_offsets"`
+	NotifyAt      []dateTime         `json:"notify_at"`
+	Attachments   []*attachment      `json:"attachments"`
+	CreatorID     int64              `json:"creator_id"`
+	UpdatedBy     int64              `json:"updated_by"`
+	CreatedAt     dateTime           `json:"created_at"`
+	UpdatedAt     dateTime           `json:"updated_at"`
+	Version       int64              `json:"version"`
 }
 
 func mapToEventsResp(event *model.Event) (*eventResp, error) {
-	notifications, _ := mapSlice(event.Notifications, func(d time.Duration) (duration, error) {
-		return duration(d), nil
-	})
-
 	attachments := make([]*attachment, len(event.Attachments))
 	for i, a := range event.Attachments {
 		attachments[i] = &attachment{
@@ -59,6 +69,12 @@ func mapToEventsResp(event *model.Event) (*eventResp, error) {
 		}
 	}
 
+	var recurrenceID *dateTime
+	if event.RecurrenceID != nil {
+		id := dateTime(*event.RecurrenceID)
+		recurrenceID = &id
+	}
+
 	return &eventResp{
 		ID:            event.ID,
 		GroupID:       event.GroupID,
@@ -68,12 +84,160 @@ func mapToEventsResp(event *model.Event) (*eventResp, error) {
 		AllDay:        event.AllDay,
 		From:          dateTime(event.From),
 		To:            dateTime(event.To),
+		TimeZone:      event.TimeZone,
 		RepeatType:    event.RepeatType,
-		Notifications: notifications,
+		Recurrence:    mapToRecurrenceResp(event.Recurrence),
+		RecurrenceID:  recurrenceID,
+		Notifications: mapToNotificationTypesResp(event.Notifications),
+		NotifyOffsets: mapToDurationsResp(event.Notifications),
+		NotifyAt:      mapToDateTimesResp(event.NotifyAt),
 		Attachments:   attachments,
+		CreatorID:     event.CreatorID,
+		UpdatedBy:     event.UpdatedBy,
+		CreatedAt:     dateTime(event.CreatedAt),
+		UpdatedAt:     dateTime(event.UpdatedAt),
+		Version:       event.Version,
 	}, nil
 }
 
+type weekdayNum struct {
+	Weekday time.Weekday `json:"weekday"`
+	N       int          `json:"n"`
+}
+
+type recurrence struct {
+	Frequency  model.Frequency `json:"frequency"`
+	Interval   int             `json:"interval"`
+	ByDay      []weekdayNum    `json:"by_day"`
+	ByMonthDay []int           `json:"by_month_day"`
+	BySetPos   []int           `json:"by_set_pos"`
+	Count      int             `json:"count"`
+	Until      *dateTime       `json:"until"`
+}
+
+func mapToRecurrenceResp(r *model.Recurrence) *recurrence {
+	if r == nil {
+		return nil
+	}
+
+	byDay := make([]weekdayNum, len(r.ByDay))
+	for i, d := range r.ByDay {
+		byDay[i] = weekdayNum{
+			Weekday: d.Weekday,
+			N:       d.N,
+		}
+	}
+
+	res := &recurrence{
+		Frequency:  r.Frequency,
+		Interval:   r.Interval,
+		ByDay:      byDay,
+		ByMonthDay: r.ByMonthDay,
+		BySetPos:   r.BySetPos,
+		Count:      r.Count,
+	}
+
+	if r.Until != nil {
+		until := dateTime(*r.Until)
+		res.Until = &until
+	}
+
+	return res
+}
+
+func mapToRecurrence(r *recurrence) *model.Recurrence {
+	if r == nil {
+		return nil
+	}
+
+	byDay := make([]model.WeekdayNum, len(r.ByDay))
+	for i, d := range r.ByDay {
+		byDay[i] = model.WeekdayNum{
+			Weekday: d.Weekday,
+			N:       d.N,
+		}
+	}
+
+	res := &model.Recurrence{
+		Frequency:  r.Frequency,
+		Interval:   r.Interval,
+		ByDay:      byDay,
+		ByMonthDay: r.ByMonthDay,
+		BySetPos:   r.BySetPos,
+		Count:      r.Count,
+	}
+
+	if r.Interval == 0 {
+		res.Interval = 1
+	}
+
+	if r.Until != nil {
+		until := time.Time(*r.Until)
+		res.Until = &until
+	}
+
+	return res
+}
+
+type repeatEnd struct {
+	Until *dateTime `json:"until"`
+	Count int       `json:"count"`
+}
+
+func mapToRepeatEnd(e *repeatEnd) *model.RepeatEnd {
+	if e == nil {
+		return nil
+	}
+
+	res := &model.RepeatEnd{Count: e.Count}
+	if e.Until != nil {
+		until := time.Time(*e.Until)
+		res.Until = &until
+	}
+
+	return res
+}
+
+func validateRepeatEnd(v *validator.Validator, e *repeatEnd, from time.Time) {
+	v.Check(e.Count >= 0, "repeat_end.count", "count must be positive")
+	v.Check(e.Count == 0 || e.Until == nil, "repeat_end.until", "only one of count and until can be provided")
+	v.Check(e.Until == nil || !time.Time(*e.Until).Before(from), "repeat_end.until", "until must not be before event start")
+}
+
+func validateRecurrence(v *validator.Validator, r *recurrence, from time.Time) {
+	v.Check(r.Frequency >= model.FrequencyDaily && r.Frequency <= model.FrequencyYearly, "recurrence.frequency", "unknown frequency")
+	v.Check(r.Interval >= 0, "recurrence.interval", "interval must not be negative; 0 means 1")
+	v.Check(r.Count >= 0, "recurrence.count", "count must be positive")
+	v.Check(r.Count == 0 || r.Until == nil, "recurrence.until", "only one of count and until can be provided")
+	v.Check(r.Until == nil || !time.Time(*r.Until).Before(from), "recurrence.until", "until must not be before event start")
+
+	for _, d := range r.ByDay {
+		v.Check(d.Weekday >= time.Sunday && d.Weekday <= time.Saturday, "recurrence.by_day", "unknown weekday")
+		v.Check(d.N >= -53 && d.N <= 53, "recurrence.by_day", "weekday ordinal must be between -53 and 53")
+		v.Check(d.N == 0 || r.Frequency == model.FrequencyMonthly || r.Frequency == model.FrequencyYearly,
+			"recurrence.by_day", "weekday ordinal is allowed only for monthly and yearly rules")
+	}
+
+	for _, d := range r.ByMonthDay {
+		v.Check(d != 0 && d >= -31 && d <= 31, "recurrence.by_month_day", "month day must be between -31 and 31")
+	}
+	v.Check(len(r.ByMonthDay) == 0 || r.Frequency != model.FrequencyWeekly, "recurrence.by_month_day", "month day is not allowed for weekly rules")
+
+	for _, p := range r.BySetPos {
+		v.Check(p != 0 && p >= -366 && p <= 366, "recurrence.by_set_pos", "set position must be between -366 and 366")
+	}
+	v.Check(len(r.BySetPos) == 0 || len(r.ByDay) != 0 || len(r.ByMonthDay) != 0, "recurrence.by_set_pos", "set position requires by_day or by_month_day")
+}
+
+func validTimeZone(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
 type dateTime time.Time
 
 var dateTimeFormat = "2006-01-02T15:04:05-07:00"
@@ -93,63 +257,169 @@ func (d *dateTime) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-type durationValue int
+// localTimeLayout is used for times of day in the time zone of the user, such as digest times.
+const localTimeLayout = "15:04"
 
-const (
-	durationValue5Minutes durationValue = iota
-	durationValue10Minutes
-	durationValue15Minutes
-	durationValue30Minutes
-	durationValueHour
-	durationValueDay
-)
+// parseMinutes returns minutes after the midnight of the time of day.
+func parseMinutes(s string) (int, error) {
+	t, err := time.Parse(localTimeLayout, s)
+	if err != nil {
+		return 0, err
+	}
 
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatMinutes(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// duration is a reminder offset before the event start in seconds.
 type duration time.Duration
 
 func (d duration) MarshalJSON() ([]byte, error) {
-	var val durationValue
-	switch time.Duration(d) {
-	case 5 * time.Minute:
-		val = durationValue5Minutes
-	case 10 * time.Minute:
-		val = durationValue10Minutes
-	case 15 * time.Minute:
-		val = durationValue15Minutes
-	case 30 * time.Minute:
-		val = durationValue30Minutes
-	case 1 * time.Hour:
-		val = durationValueHour
-	case 24 * time.Hour:
-		val = durationValueDay
-	default:
-		return nil, fmt.Errorf("unsupported duration: %v", d)
-	}
-
-	return []byte(fmt.Sprintf("%d", val)), nil
+	return []byte(strconv.FormatInt(int64(time.Duration(d)/time.Second), 10)), nil
 }
 
 func (d *duration) UnmarshalJSON(b []byte) error {
-	val, err := strconv.Atoi(string(b))
+	val, err := strconv.ParseInt(string(b), 10, 64)
 	if err != nil {
 		return err
 	}
 
-	switch durationValue(val) {
-	case durationValue5Minutes:
-		*d = duration(5 * time.Minute)
-	case durationValue10Minutes:
-		*d = duration(10 * time.Minute)
-	case durationValue15Minutes:
-		*d = duration(15 * time.Minute)
-	case durationValue30Minutes:
-		*d = duration(30 * time.Minute)
-	case durationValueHour:
-		*d = duration(1 * time.Hour)
-	case durationValueDay:
-		*d = duration(24 * time.Hour)
-	default:
-		return fmt.Errorf("unknow duration value: %v", val)
+	*d = duration(time.Duration(val) * time.Second)
+	return nil
+}
+
+// validNotifyOffset reports whether the reminder offset can be sent by the notifications sender,
+// that checks reminders once a minute.
+func validNotifyOffset(d time.Duration) bool {
+	return d >= 0 && d%time.Minute == 0 && d <= config.MaxNotifyOffset()
+}
+
+// notificationType is the reminder offset of old clients, an index in model.NotificationTypes.
+// New clients send offsets in seconds in notify_offsets, that take precedence.
+type notificationType int
+
+// notifyOffsets returns offsets in seconds if they are set, otherwise offsets of legacy notification types.
+func notifyOffsets(offsets []duration, types []notificationType) []time.Duration {
+	if offsets != nil {
+		return mapToDurations(offsets)
 	}
 
-	return nil
+	res, _ := mapSlice(types, func(t notificationType) (time.Duration, error) {
+		return model.NotificationTypes[t], nil
+	})
+
+	return res
+}
+
+// mergeNotifyOffsets returns offsets of the update, stored offsets are kept if the update has none.
+// Old clients send only legacy types, so the stored offsets they don't know are kept as well.
+func mergeNotifyOffsets(stored []time.Duration, offsets []duration, types []notificationType) []time.Duration {
+	if offsets != nil {
+		return mapToDurations(offsets)
+	}
+	if types == nil {
+		return stored
+	}
+
+	res := notifyOffsets(nil, types)
+	for _, d := range stored {
+		if !legacyNotifyOffset(d) {
+			res = append(res, d)
+		}
+	}
+
+	return res
+}
+
+func legacyNotifyOffset(d time.Duration) bool {
+	for _, t := range model.NotificationTypes {
+		if d == t {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTimes returns times of the update, stored times are kept if the update has none.
+func mergeTimes(stored []time.Time, times []dateTime) []time.Time {
+	if times == nil {
+		return stored
+	}
+	return mapToTimes(times)
+}
+
+// maxNotifyAt limits reminders at the absolute time, each of them is scheduled separately.
+const maxNotifyAt = 10
+
+func validateNotifications(v *validator.Validator, offsets []duration, types []notificationType, notifyAt []dateTime) {
+	for _, n := range offsets {
+		v.Check(validNotifyOffset(time.Duration(n)), "notify_offsets",
+			fmt.Sprintf("notification offset must be a whole number of minutes not greater than %v", config.MaxNotifyOffset()))
+	}
+
+	for _, t := range types {
+		v.Check(t >= 0 && int(t) < len(model.NotificationTypes), "notifications", fmt.Sprintf("unknown notification type %d", t))
+	}
+
+	v.Check(len(notifyAt) <= maxNotifyAt, "notify_at", fmt.Sprintf("at most %d reminders at the time can be set", maxNotifyAt))
+
+	now := time.Now()
+	seen := make(map[int64]struct{}, len(notifyAt))
+	for _, t := range notifyAt {
+		ts := time.Time(t).Unix()
+		_, ok := seen[ts]
+		v.Check(!ok, "notify_at", "reminder times must be unique")
+		v.Check(time.Time(t).After(now), "notify_at", "reminder time must be in the future")
+		seen[ts] = struct{}{}
+	}
+}
+
+// mapToNotificationTypesResp keeps only offsets that old clients can show.
+func mapToNotificationTypesResp(durations []time.Duration) []notificationType {
+	res := make([]notificationType, 0, len(durations))
+	for _, d := range durations {
+		for i, t := range model.NotificationTypes {
+			if d == t {
+				res = append(res, notificationType(i))
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+func mapToDurationsResp(durations []time.Duration) []duration {
+	res, _ := mapSlice(durations, func(d time.Duration) (duration, error) {
+		return duration(d), nil
+	})
+
+	return res
+}
+
+func mapToDurations(durations []duration) []time.Duration {
+	res, _ := mapSlice(durations, func(d duration) (time.Duration, error) {
+		return time.Duration(d), nil
+	})
+
+	return res
+}
+
+func mapToDateTimesResp(times []time.Time) []dateTime {
+	res, _ := mapSlice(times, func(t time.Time) (dateTime, error) {
+		return dateTime(t), nil
+	})
+
+	return res
+}
+
+func mapToTimes(times []dateTime) []time.Time {
+	res, _ := mapSlice(times, func(t dateTime) (time.Time, error) {
+		return time.Time(t), nil
+	})
+
+	return res
 }
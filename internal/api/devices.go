@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+)
+
+type deviceResp struct {
+	ID         int64    `json:"id"`
+	Platform   string   `json:"platform"`
+	AppVersion string   `json:"app_version"`
+	CreatedAt  dateTime `json:"created_at"`
+	LastSeenAt dateTime `json:"last_seen_at"`
+}
+
+var platforms = []string{
+	string(model.PlatformAndroid),
+	string(model.PlatformIOS),
+	string(model.PlatformWeb),
+}
+
+func (a *Api) getDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	devices, err := a.devices.GetUsersDevices(r.Context(), a.db, []int64{userID})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get devices: %w", err))
+		return
+	}
+
+	resp, _ := mapSlice(devices, func(d *model.Device) (*deviceResp, error) {
+		return &deviceResp{
+			ID:         d.ID,
+			Platform:   string(d.Platform),
+			AppVersion: d.AppVersion,
+			CreatedAt:  dateTime(d.CreatedAt),
+			LastSeenAt: dateTime(d.LastSeenAt),
+		}, nil
+	})
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// registerDeviceHandler binds the push token to the session of the given refresh token,
+// so that the device stops receiving notifications on logout.
+func (a *Api) registerDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	req := &struct {
+		RefreshToken string `json:"refresh_token"`
+		PushToken    string `json:"push_token"`
+		Platform     string `json:"platform"`
+		AppVersion   string `json:"app_version"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(req.RefreshToken != "", "refresh_token", "refresh token must be provided")
+	v.Check(req.PushToken != "", "push_token", "push token must be provided")
+	v.Check(validator.In(req.Platform, platforms...), "platform", "unknown platform")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !a.checkSession(w, r, userID, req.RefreshToken) {
+		return
+	}
+
+	device := &model.Device{
+		UserID:      userID,
+		SessionHash: hashToken(req.RefreshToken),
+		PushToken:   req.PushToken,
+		Platform:    model.Platform(req.Platform),
+		AppVersion:  req.AppVersion,
+	}
+
+	if err := a.devices.UpsertDevice(r.Context(), a.db, device); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("upsert device: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *Api) unregisterDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	req := &struct {
+		RefreshToken string `json:"refresh_token"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(req.RefreshToken != "", "refresh_token", "refresh token must be provided")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !a.checkSession(w, r, userID, req.RefreshToken) {
+		return
+	}
+
+	if err := a.devices.DeleteDevice(r.Context(), a.db, hashToken(req.RefreshToken)); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete device: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkSession writes the error response if the refresh token doesn't belong to the user.
+func (a *Api) checkSession(w http.ResponseWriter, r *http.Request, userID int64, refreshToken string) bool {
+	id, err := a.refreshTokens.Get(r.Context(), refreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.unauthorizedResponse(w, r, errors.New("no such session"))
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	if id != userID {
+		a.unauthorizedResponse(w, r, errors.New("no such session"))
+		return false
+	}
+
+	return true
+}
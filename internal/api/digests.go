@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+)
+
+type digestSettings struct {
+	Daily      bool    `json:"daily"`
+	DailyTime  string  `json:"daily_time"`
+	Weekly     bool    `json:"weekly"`
+	WeeklyDay  int     `json:"weekly_day"`
+	WeeklyTime string  `json:"weekly_time"`
+	GroupIDs   []int64 `json:"group_ids"`
+	Email      bool    `json:"email"`
+}
+
+// getDigestSettingsHandler returns defaults with both digests turned off, if user never set them.
+func (a *Api) getDigestSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	settings, err := a.digests.GetDigestSettings(r.Context(), a.db, model.DigestSettingsFilter{
+		UserIDs: []int64{user.ID},
+	})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get digest settings: %w", err))
+		return
+	}
+
+	s := &model.DigestSettings{
+		UserID:     user.ID,
+		DailyTime:  7 * 60,
+		WeeklyDay:  time.Sunday,
+		WeeklyTime: 18 * 60,
+	}
+	if len(settings) != 0 {
+		s = settings[0]
+	}
+
+	groupIDs := s.GroupIDs
+	if groupIDs == nil {
+		groupIDs = []int64{}
+	}
+
+	resp := &digestSettings{
+		Daily:      s.Daily,
+		DailyTime:  formatMinutes(s.DailyTime),
+		Weekly:     s.Weekly,
+		WeeklyDay:  int(s.WeeklyDay),
+		WeeklyTime: formatMinutes(s.WeeklyTime),
+		GroupIDs:   groupIDs,
+		Email:      s.Email,
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateDigestSettingsHandler sets digests of the user, times are local to the time zone of the user.
+func (a *Api) updateDigestSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	req := &digestSettings{}
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	groups, err := a.groups.GetUserGroups(r.Context(), a.db, user.ID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get groups: %w", err))
+		return
+	}
+
+	userGroups := make(map[int64]struct{}, len(groups))
+	for _, g := range groups {
+		userGroups[g.ID] = struct{}{}
+	}
+
+	v := validator.New()
+
+	dailyTime, err := parseMinutes(req.DailyTime)
+	v.Check(err == nil, "daily_time", "must be time in HH:MM format")
+	weeklyTime, err := parseMinutes(req.WeeklyTime)
+	v.Check(err == nil, "weekly_time", "must be time in HH:MM format")
+	v.Check(req.WeeklyDay >= int(time.Sunday) && req.WeeklyDay <= int(time.Saturday), "weekly_day", "must be from 0 (sunday) to 6 (saturday)")
+
+	for _, id := range req.GroupIDs {
+		_, ok := userGroups[id]
+		v.Check(ok, "group_ids", fmt.Sprintf("user is not a member of group %v", id))
+	}
+
+	if req.Email {
+		available := make([]string, len(a.channels))
+		for i, c := range a.channels {
+			available[i] = string(c)
+		}
+		v.Check(validator.In(string(model.ChannelEmail), available...), "email", "email is not available")
+	}
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	settings := &model.DigestSettings{
+		UserID:     user.ID,
+		Daily:      req.Daily,
+		DailyTime:  dailyTime,
+		Weekly:     req.Weekly,
+		WeeklyDay:  time.Weekday(req.WeeklyDay),
+		WeeklyTime: weeklyTime,
+		GroupIDs:   req.GroupIDs,
+		Email:      req.Email,
+	}
+
+	if err := a.digests.UpsertDigestSettings(r.Context(), a.db, settings); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("upsert digest settings: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -56,6 +56,15 @@ func (a *Api) forbiddenResponse(w http.ResponseWriter, r *http.Request, message
 	a.clientErrorResponse(w, r, http.StatusForbidden, message)
 }
 
+func (a *Api) editConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource was modified by another request, please reload it and try again"
+	a.clientErrorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+func (a *Api) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	a.clientErrorResponse(w, r, http.StatusPreconditionRequired, errMissingIfMatch.Error())
+}
+
 func (a *Api) fileTooBigResponse(w http.ResponseWriter, r *http.Request) {
 	a.clientErrorResponse(w, r, http.StatusConflict, "file is too big")
 }
@@ -15,23 +15,34 @@ var errCantRetrieveUserGroups = errors.New("can't retrieve user groups from cont
 var errCantRetrieveEvent = errors.New("can't retrieve event from context")
 
 func (a *Api) createEventHandler(w http.ResponseWriter, r *http.Request) {
-	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]struct{})
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]model.GroupRole)
 	if !ok {
 		a.serverErrorResponse(w, r, errCantRetrieveUserGroups)
 		return
 	}
 
 	req := &struct {
-		GroupID       int64            `json:"group_id"`
-		EventType     model.EventType  `json:"event_type"`
-		Title         string           `json:"title"`
-		Description   string           `json:"description"`
-		AllDay        bool             `json:"all_day"`
-		From          dateTime         `json:"from"`
-		To            dateTime         `json:"to"`
-		RepeatType    model.RepeatType `json:"repeat_type"`
-		Notifications []duration       `json:"notifications"`
-		Attachments   []*attachment    `json:"attachments"`
+		GroupID       int64              `json:"group_id"`
+		EventType     model.EventType    `json:"event_type"`
+		Title         string             `json:"title"`
+		Description   string             `json:"description"`
+		AllDay        bool               `json:"all_day"`
+		From          dateTime           `json:"from"`
+		To            dateTime           `json:"to"`
+		TimeZone      string             `json:"time_zone"`
+		RepeatType    model.RepeatType   `json:"repeat_type"`
+		Recurrence    *recurrence        `json:"recurrence"`
+		RepeatEnd     *repeatEnd         `json:"repeat_end"`
+		Notifications []notificationType `json:"notifications"`
+		NotifyOffsets []duration         `json:"notify_offsets"`
+		NotifyAt      []dateTime         `json:"notify_at"`
+		Attachments   []*attachment      `json:"attachments"`
 	}{}
 
 	if err := a.readJSON(w, r, req); err != nil {
@@ -41,8 +52,9 @@ func (a *Api) createEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.New()
 
-	_, ok = userGroups[req.GroupID]
+	role, ok := userGroups[req.GroupID]
 	v.Check(ok, "group_id", "user does not have access to group")
+	v.Check(!ok || hasRole(role, editorRoles), "group_id", "user can't edit events of the group")
 	v.Check(len(req.Title) != 0, "title", "title must be provided")
 	v.Check(!time.Time(req.From).IsZero(), "from", "from must be provided")
 
@@ -50,14 +62,37 @@ func (a *Api) createEventHandler(w http.ResponseWriter, r *http.Request) {
 		v.Check(!time.Time(req.To).IsZero(), "to", "to must be provided")
 	}
 
+	v.Check(validTimeZone(req.TimeZone), "time_zone", "time zone must be valid IANA time zone")
+	v.Check(req.RepeatType >= model.RepeatTypeNone && req.RepeatType <= model.RepeatTypeCustom, "repeat_type", "unknown repeat type")
+	v.Check(req.RepeatType != model.RepeatTypeCustom || req.Recurrence != nil, "recurrence", "recurrence must be provided for custom repeat type")
+	if req.Recurrence != nil {
+		validateRecurrence(v, req.Recurrence, time.Time(req.From))
+	}
+	if req.RepeatEnd != nil {
+		v.Check(req.RepeatType != model.RepeatTypeNone || req.Recurrence != nil, "repeat_end", "repeat end is allowed only for repeating events")
+		validateRepeatEnd(v, req.RepeatEnd, time.Time(req.From))
+	}
+	validateNotifications(v, req.NotifyOffsets, req.Notifications, req.NotifyAt)
+
 	if !v.Valid() {
 		a.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	notifications, _ := mapSlice(req.Notifications, func(d duration) (time.Duration, error) {
-		return time.Duration(d), nil
-	})
+	repeatType := req.RepeatType
+	if req.Recurrence != nil {
+		repeatType = model.RepeatTypeCustom
+	}
+
+	timeZone := req.TimeZone
+	if timeZone == "" {
+		user, err := a.users.GetUserByID(r.Context(), a.db, userID)
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get user: %w", err))
+			return
+		}
+		timeZone = user.TimeZone
+	}
 
 	attachments, _ := mapSlice(req.Attachments, func(a *attachment) (*model.Attachment, error) {
 		return &model.Attachment{
@@ -66,7 +101,7 @@ func (a *Api) createEventHandler(w http.ResponseWriter, r *http.Request) {
 		}, nil
 	})
 
-	if _, err := a.eventsService.CreateEvent(r.Context(), &model.EventCreate{
+	if _, err := a.eventsService.CreateEvent(r.Context(), userID, &model.EventCreate{
 		GroupID:       req.GroupID,
 		EventType:     req.EventType,
 		Title:         req.Title,
@@ -74,8 +109,12 @@ func (a *Api) createEventHandler(w http.ResponseWriter, r *http.Request) {
 		AllDay:        req.AllDay,
 		From:          time.Time(req.From),
 		To:            time.Time(req.To),
-		RepeatType:    req.RepeatType,
-		Notifications: notifications,
+		TimeZone:      timeZone,
+		RepeatType:    repeatType,
+		Recurrence:    mapToRecurrence(req.Recurrence),
+		RepeatEnd:     mapToRepeatEnd(req.RepeatEnd),
+		Notifications: notifyOffsets(req.NotifyOffsets, req.Notifications),
+		NotifyAt:      mapToTimes(req.NotifyAt),
 		Attachments:   attachments,
 	}); err != nil {
 		a.serverErrorResponse(w, r, fmt.Errorf("create event: %w", err))
@@ -86,7 +125,7 @@ func (a *Api) createEventHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *Api) getEventsHandler(w http.ResponseWriter, r *http.Request) {
-	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]struct{})
+	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]model.GroupRole)
 	if !ok {
 		a.serverErrorResponse(w, r, errCantRetrieveUserGroups)
 		return
@@ -150,6 +189,15 @@ func parseEventsQuery(r *http.Request) (*model.EventsFilter, error) {
 		}
 	}
 
+	vals = r.URL.Query()["creator_ids"]
+	res.CreatorIDs = make([]int64, len(vals))
+	for i, v := range vals {
+		res.CreatorIDs[i], err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creator id %v", v)
+		}
+	}
+
 	return res, nil
 }
 
@@ -161,34 +209,48 @@ func (a *Api) getEventHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp, _ := mapToEventsResp(event)
-	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+	headers := http.Header{}
+	headers.Set("ETag", etag(event.Version))
+
+	if err := a.writeJSON(w, http.StatusOK, resp, headers); err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
 func (a *Api) updateEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
 	event, ok := r.Context().Value(contextKeyEvent).(*model.Event)
 	if !ok {
 		a.serverErrorResponse(w, r, errCantRetrieveEvent)
 		return
 	}
 
-	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]struct{})
+	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]model.GroupRole)
 	if !ok {
 		a.serverErrorResponse(w, r, errCantRetrieveUserGroups)
 		return
 	}
 
 	req := &struct {
-		OnlyUpdateInstance bool            `json:"only_update_instance"`
-		GroupID            int64           `json:"group_id"`
-		EventType          model.EventType `json:"event_type"`
-		Title              string          `json:"title"`
-		Description        string          `json:"description"`
-		AllDay             bool            `json:"all_day"`
-		From               dateTime        `json:"from"`
-		To                 dateTime        `json:"to"`
-		Notifications      []duration      `json:"notifications"`
+		OnlyUpdateInstance bool               `json:"only_update_instance"`
+		UpdateFollowing    bool               `json:"update_following"`
+		GroupID            int64              `json:"group_id"`
+		EventType          model.EventType    `json:"event_type"`
+		Title              string             `json:"title"`
+		Description        string             `json:"description"`
+		AllDay             bool               `json:"all_day"`
+		From               dateTime           `json:"from"`
+		To                 dateTime           `json:"to"`
+		TimeZone           string             `json:"time_zone"`
+		RepeatEnd          *repeatEnd         `json:"repeat_end"`
+		Notifications      []notificationType `json:"notifications"`
+		NotifyOffsets      []duration         `json:"notify_offsets"`
+		NotifyAt           []dateTime         `json:"notify_at"`
 	}{}
 
 	if err := a.readJSON(w, r, req); err != nil {
@@ -198,8 +260,9 @@ func (a *Api) updateEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.New()
 
-	_, ok = userGroups[req.GroupID]
+	role, ok := userGroups[req.GroupID]
 	v.Check(ok, "group_id", "user does not have access to group")
+	v.Check(!ok || hasRole(role, editorRoles), "group_id", "user can't edit events of the group")
 	v.Check(len(req.Title) != 0, "title", "title must be provided")
 	v.Check(!time.Time(req.From).IsZero(), "from", "from must be provided")
 
@@ -207,18 +270,29 @@ func (a *Api) updateEventHandler(w http.ResponseWriter, r *http.Request) {
 		v.Check(!time.Time(req.To).IsZero(), "to", "to must be provided")
 	}
 
+	v.Check(!req.OnlyUpdateInstance || !req.UpdateFollowing, "update_following", "only one update mode can be selected")
+	v.Check(validTimeZone(req.TimeZone), "time_zone", "time zone must be valid IANA time zone")
+	if req.RepeatEnd != nil {
+		v.Check(event.RepeatType != model.RepeatTypeNone, "repeat_end", "repeat end is allowed only for repeating events")
+		v.Check(!req.OnlyUpdateInstance, "repeat_end", "repeat end can't be changed for a single instance")
+		validateRepeatEnd(v, req.RepeatEnd, time.Time(req.From))
+	}
+	validateNotifications(v, req.NotifyOffsets, req.Notifications, req.NotifyAt)
+
 	if !v.Valid() {
 		a.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	notifications, _ := mapSlice(req.Notifications, func(d duration) (time.Duration, error) {
-		return time.Duration(d), nil
-	})
-
 	id, ts, err := splitID(event.ID)
 	if err != nil {
-		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", event))
+		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", err))
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		a.ifMatchErrorResponse(w, r, err)
 		return
 	}
 
@@ -230,17 +304,41 @@ func (a *Api) updateEventHandler(w http.ResponseWriter, r *http.Request) {
 		AllDay:        req.AllDay,
 		From:          time.Time(req.From),
 		To:            time.Time(req.To),
-		Notifications: notifications,
+		TimeZone:      req.TimeZone,
+		RepeatEnd:     mapToRepeatEnd(req.RepeatEnd),
+		Notifications: mergeNotifyOffsets(event.Notifications, req.NotifyOffsets, req.Notifications),
+		NotifyAt:      mergeTimes(event.NotifyAt, req.NotifyAt),
 	}
 
-	if event.RepeatType == model.RepeatTypeNone || !req.OnlyUpdateInstance {
-		if err := a.eventsService.UpdateEvent(r.Context(), id, ts, updateEvent); err != nil {
-			a.serverErrorResponse(w, r, fmt.Errorf("update event: %w", err))
+	switch {
+	case event.RepeatType == model.RepeatTypeNone || !req.OnlyUpdateInstance && !req.UpdateFollowing:
+		if err := a.eventsService.UpdateEvent(r.Context(), userID, id, version, ts, updateEvent); err != nil {
+			switch {
+			case errors.Is(err, model.ErrEditConflict):
+				a.editConflictResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("update event: %w", err))
+			}
 			return
 		}
-	} else {
-		if err := a.eventsService.UpdateEventInstance(r.Context(), id, ts, updateEvent); err != nil {
-			a.serverErrorResponse(w, r, fmt.Errorf("update event instance: %w", err))
+	case req.UpdateFollowing:
+		if err := a.eventsService.UpdateEventFollowing(r.Context(), userID, id, version, ts, updateEvent); err != nil {
+			switch {
+			case errors.Is(err, model.ErrEditConflict):
+				a.editConflictResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("update following events: %w", err))
+			}
+			return
+		}
+	default:
+		if err := a.eventsService.UpdateEventInstance(r.Context(), userID, id, version, ts, updateEvent); err != nil {
+			switch {
+			case errors.Is(err, model.ErrEditConflict):
+				a.editConflictResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("update event instance: %w", err))
+			}
 			return
 		}
 	}
@@ -249,6 +347,12 @@ func (a *Api) updateEventHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *Api) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
 	event, ok := r.Context().Value(contextKeyEvent).(*model.Event)
 	if !ok {
 		a.serverErrorResponse(w, r, errCantRetrieveEvent)
@@ -257,6 +361,7 @@ func (a *Api) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	req := &struct {
 		OnlyDeleteInstance bool `json:"only_delete_instance"`
+		DeleteFollowing    bool `json:"delete_following"`
 	}{}
 
 	if err := a.readJSON(w, r, req); err != nil {
@@ -264,20 +369,55 @@ func (a *Api) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validator.New()
+	v.Check(!req.OnlyDeleteInstance || !req.DeleteFollowing, "delete_following", "only one delete mode can be selected")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	id, ts, err := splitID(event.ID)
 	if err != nil {
-		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", event))
+		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", err))
 		return
 	}
 
-	if event.RepeatType == model.RepeatTypeNone || !req.OnlyDeleteInstance {
-		if err := a.eventsService.DeleteEvent(r.Context(), id); err != nil {
-			a.serverErrorResponse(w, r, fmt.Errorf("delete event: %w", err))
+	version, err := readIfMatch(r)
+	if err != nil {
+		a.ifMatchErrorResponse(w, r, err)
+		return
+	}
+
+	switch {
+	case event.RepeatType == model.RepeatTypeNone || !req.OnlyDeleteInstance && !req.DeleteFollowing:
+		if err := a.eventsService.DeleteEvent(r.Context(), userID, id, version); err != nil {
+			switch {
+			case errors.Is(err, model.ErrEditConflict):
+				a.editConflictResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("delete event: %w", err))
+			}
+			return
+		}
+	case req.DeleteFollowing:
+		if err := a.eventsService.DeleteEventFollowing(r.Context(), userID, id, version, ts); err != nil {
+			switch {
+			case errors.Is(err, model.ErrEditConflict):
+				a.editConflictResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("delete following events: %w", err))
+			}
 			return
 		}
-	} else {
-		if err := a.eventsService.DeleteEventInstance(r.Context(), id, ts); err != nil {
-			a.serverErrorResponse(w, r, fmt.Errorf("update event instance: %w", err))
+	default:
+		if err := a.eventsService.DeleteEventInstance(r.Context(), userID, id, version, ts); err != nil {
+			switch {
+			case errors.Is(err, model.ErrEditConflict):
+				a.editConflictResponse(w, r)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("update event instance: %w", err))
+			}
 			return
 		}
 	}
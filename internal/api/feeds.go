@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/icalendar"
+	"github.com/go-chi/chi/v5"
+)
+
+const personalFeedName = "Shared Planner"
+
+type feedResp struct {
+	GroupID int64  `json:"group_id,omitempty"`
+	URL     string `json:"url"`
+}
+
+// feedHandler serves iCalendar feed by its secret token, so that it can be subscribed to without authorization.
+func (a *Api) feedHandler(w http.ResponseWriter, r *http.Request) {
+	feed, err := a.feeds.GetFeedByToken(r.Context(), a.db, chi.URLParam(r, "token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("get feed: %w", err))
+		}
+		return
+	}
+
+	groups, err := a.groups.GetUserGroups(r.Context(), a.db, feed.UserID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get groups: %w", err))
+		return
+	}
+
+	name := personalFeedName
+	var groupIDs []int64
+	for _, g := range groups {
+		if feed.GroupID == 0 || feed.GroupID == g.ID {
+			groupIDs = append(groupIDs, g.ID)
+		}
+		if feed.GroupID == g.ID {
+			name = g.Name
+		}
+	}
+
+	// user has left the group, feed of which was requested
+	if feed.GroupID != 0 && len(groupIDs) == 0 {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	var series []*model.Series
+	if len(groupIDs) != 0 {
+		series, err = a.eventsService.GetSeries(r.Context(), model.EventsFilter{GroupIDs: groupIDs})
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get series: %w", err))
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := icalendar.Encode(&buf, name, series, publicURL(r)); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("encode calendar: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (a *Api) getFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	feeds, err := a.feeds.GetUserFeeds(r.Context(), a.db, userID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get feeds: %w", err))
+		return
+	}
+
+	resp, _ := mapSlice(feeds, func(f *model.Feed) (*feedResp, error) {
+		return &feedResp{
+			GroupID: f.GroupID,
+			URL:     feedURL(r, f.Token),
+		}, nil
+	})
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *Api) rotateUserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	a.rotateFeed(w, r, userID, 0)
+}
+
+func (a *Api) revokeUserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	a.revokeFeed(w, r, userID, 0)
+}
+
+func (a *Api) rotateGroupFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	a.rotateFeed(w, r, userID, group.ID)
+}
+
+func (a *Api) revokeGroupFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	a.revokeFeed(w, r, userID, group.ID)
+}
+
+// rotateFeed creates a new token for the feed, the old link stops working.
+func (a *Api) rotateFeed(w http.ResponseWriter, r *http.Request, userID int64, groupID int64) {
+	token, err := a.generateFeedToken(r.Context(), userID, groupID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("generate feed token: %w", err))
+		return
+	}
+
+	resp := &feedResp{
+		GroupID: groupID,
+		URL:     feedURL(r, token),
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *Api) revokeFeed(w http.ResponseWriter, r *http.Request, userID int64, groupID int64) {
+	if err := a.feeds.DeleteFeed(r.Context(), a.db, userID, groupID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete feed: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *Api) generateFeedToken(ctx context.Context, userID int64, groupID int64) (string, error) {
+	for {
+		token, err := a.generateRandomString(config.FeedTokenLength())
+		if err != nil {
+			return "", err
+		}
+
+		if err := a.feeds.UpsertFeed(ctx, a.db, &model.Feed{
+			Token:   token,
+			UserID:  userID,
+			GroupID: groupID,
+		}); err != nil {
+			if errors.Is(err, model.ErrAlreadyExists) {
+				continue
+			}
+			return "", err
+		}
+
+		return token, nil
+	}
+}
+
+func feedURL(r *http.Request, token string) string {
+	return fmt.Sprintf("%s/feeds/%s.ics", publicURL(r), token)
+}
@@ -1,16 +1,23 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
 	"github.com/gerow/go-color"
 )
 
-var errCantRetrieveGroup = errors.New("can't retrieve group from context")
+var (
+	errCantRetrieveGroup = errors.New("can't retrieve group from context")
+	errCantRetrieveRole  = errors.New("can't retrieve role from context")
+)
 
 func (a *Api) getUserGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	type getUserGroupsResponse struct {
@@ -115,27 +122,32 @@ func (a *Api) createGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	toAdd := []int64{userID}
-	toAddMap := make(map[int64]struct{})
-	toAddMap[userID] = struct{}{}
+	if err := a.groups.AddUserToGroup(r.Context(), tx, &model.GroupSettings{
+		UserID:  userID,
+		GroupID: groupID,
+		Color:   colorRGB,
+		Notify:  true,
+		Role:    model.GroupRoleOwner,
+	}); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("add user to group: %w", err))
+		return
+	}
+
+	// other users join the group only after accepting the invite
+	var toInvite []int64
+	toInviteMap := make(map[int64]struct{})
+	toInviteMap[userID] = struct{}{}
 
 	for _, id := range req.UsersIDs {
-		if _, ok := toAddMap[id]; !ok {
-			toAdd = append(toAdd, id)
-			toAddMap[id] = struct{}{}
+		if _, ok := toInviteMap[id]; !ok {
+			toInvite = append(toInvite, id)
+			toInviteMap[id] = struct{}{}
 		}
 	}
 
-	for _, user := range toAdd {
-		if err := a.groups.AddUserToGroup(r.Context(), tx, &model.GroupSettings{
-			UserID:  user,
-			GroupID: groupID,
-			Color:   colorRGB,
-			Notify:  true,
-		}); err != nil {
-			a.serverErrorResponse(w, r, fmt.Errorf("add user to group: %w", err))
-			return
-		}
+	if err := a.inviteUsers(r.Context(), tx, groupID, userID, toInvite, nil); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("invite users: %w", err))
+		return
 	}
 
 	if err := tx.Commit(r.Context()); err != nil {
@@ -184,20 +196,27 @@ func (a *Api) getGroupHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := &struct {
-		ID        int64       `json:"id"`
-		Name      string      `json:"name"`
-		CreatorID int64       `json:"creator_id"`
-		Color     string      `json:"color"`
-		Users     []*userResp `json:"users"`
+		ID        int64                     `json:"id"`
+		Name      string                    `json:"name"`
+		CreatorID int64                     `json:"creator_id"`
+		Color     string                    `json:"color"`
+		Users     []*userResp               `json:"users"`
+		Roles     map[int64]model.GroupRole `json:"roles"`
+		Version   int64                     `json:"version"`
 	}{
 		ID:        group.ID,
 		Name:      group.Name,
 		CreatorID: group.CreatorID,
 		Color:     "#" + settings[0].Color.ToHTML(),
 		Users:     userResps,
+		Roles:     group.Roles,
+		Version:   group.Version,
 	}
 
-	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+	headers := http.Header{}
+	headers.Set("ETag", etag(group.Version))
+
+	if err := a.writeJSON(w, http.StatusOK, resp, headers); err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
@@ -215,6 +234,23 @@ func (a *Api) updateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	role, ok := r.Context().Value(contextKeyRole).(model.GroupRole)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveRole)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		a.ifMatchErrorResponse(w, r, err)
+		return
+	}
+
+	if version != group.Version {
+		a.editConflictResponse(w, r)
+		return
+	}
+
 	req := &struct {
 		Name     string  `json:"name"`
 		UsersIDs []int64 `json:"users_ids"`
@@ -227,9 +263,8 @@ func (a *Api) updateGroupHandler(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.New()
 	v.Check(len(req.Name) != 0, "name", "name must be provided")
-	v.Check(group.Name == req.Name || group.CreatorID == userID, "name", "only creator can change name")
 
-	toAdd, toRemove, err := calculateUsers(group, req.UsersIDs, userID)
+	toAdd, toRemove, err := calculateUsers(group, req.UsersIDs, role)
 	if err != nil {
 		v.AddError("users_ids", err.Error())
 	}
@@ -239,39 +274,58 @@ func (a *Api) updateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	settings, err := a.groups.GetUserGroupSettings(r.Context(), a.db, model.UserGroupSettingsFilter{
-		UserIDs:  []int64{group.CreatorID},
-		GroupIDs: []int64{group.ID},
-	})
+	tx, err := a.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		a.serverErrorResponse(w, r, fmt.Errorf("get group settings: %w", err))
+		a.serverErrorResponse(w, r, fmt.Errorf("begin tx: %w", err))
 		return
 	}
-	if len(settings) != 1 {
-		a.serverErrorResponse(w, r, fmt.Errorf("invalid number of group settings %d", len(settings)))
+	defer tx.Rollback(r.Context())
+
+	// members were calculated from the group read before, so it must not change until commit
+	currentVersion, err := a.groups.LockGroup(r.Context(), tx, group.ID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("lock group: %w", err))
 		return
 	}
 
-	tx, err := a.db.BeginTx(r.Context(), nil)
-	if err != nil {
-		a.serverErrorResponse(w, r, fmt.Errorf("begin tx: %w", err))
+	if currentVersion != group.Version {
+		a.editConflictResponse(w, r)
 		return
 	}
-	defer tx.Rollback(r.Context())
 
 	if err := a.groups.UpdateGroupName(r.Context(), tx, group.ID, req.Name); err != nil {
 		a.serverErrorResponse(w, r, fmt.Errorf("update group name: %w", err))
 		return
 	}
 
-	for _, id := range toAdd {
-		if err := a.groups.AddUserToGroup(r.Context(), tx, &model.GroupSettings{
-			UserID:  id,
+	var changes []*model.GroupChange
+	if req.Name != group.Name {
+		changes = append(changes, &model.GroupChange{
 			GroupID: group.ID,
-			Color:   settings[0].Color,
-			Notify:  true,
-		}); err != nil {
-			a.serverErrorResponse(w, r, fmt.Errorf("add user to group: %w", err))
+			ActorID: userID,
+			Type:    model.ChangeTypeGroupRenamed,
+			Title:   req.Name,
+		})
+	}
+	// removed members are notified too, so they are notified before the removal
+	if len(toRemove) != 0 {
+		changes = append(changes, &model.GroupChange{
+			GroupID:   group.ID,
+			ActorID:   userID,
+			Type:      model.ChangeTypeMembersRemoved,
+			MemberIDs: toRemove,
+		})
+	}
+
+	// new users join the group only after accepting the invite
+	if err := a.inviteUsers(r.Context(), tx, group.ID, userID, toAdd, nil); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("invite users: %w", err))
+		return
+	}
+
+	for _, c := range changes {
+		if err := a.notifyGroup(r.Context(), tx, c); err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("notify group: %w", err))
 			return
 		}
 	}
@@ -341,7 +395,15 @@ func (a *Api) updateGroupSettingsHandler(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
-func calculateUsers(group *model.Group, newUsers []int64, userID int64) ([]int64, []int64, error) {
+// notifyGroup schedules the push about the change for other members of the group,
+// following changes are merged into it until it is sent.
+func (a *Api) notifyGroup(ctx context.Context, q database.Queryable, change *model.GroupChange) error {
+	return a.outbox.AddChangeNotifications(ctx, q, change, time.Now().Add(config.ChangeNotifyDelay()), config.ChangeNotifyMaxDelay())
+}
+
+// calculateUsers returns members to add and to remove, role is the role of the member making the change.
+// The owner stays in the group and only the owner can remove admins.
+func calculateUsers(group *model.Group, newUsers []int64, role model.GroupRole) ([]int64, []int64, error) {
 	oldMap := make(map[int64]struct{})
 	for _, id := range group.UsersIDs {
 		oldMap[id] = struct{}{}
@@ -376,12 +438,15 @@ func calculateUsers(group *model.Group, newUsers []int64, userID int64) ([]int64
 		}
 	}
 
-	if _, ok := toRemoveMap[group.CreatorID]; ok {
-		return nil, nil, fmt.Errorf("can't remove creator")
-	}
-
-	if len(toRemove) != 0 && group.CreatorID != userID {
-		return nil, nil, fmt.Errorf("only creator can remove users from group")
+	for _, id := range toRemove {
+		switch group.Roles[id] {
+		case model.GroupRoleOwner:
+			return nil, nil, fmt.Errorf("can't remove owner")
+		case model.GroupRoleAdmin:
+			if role != model.GroupRoleOwner {
+				return nil, nil, fmt.Errorf("only owner can remove admins")
+			}
+		}
 	}
 
 	return toAdd, toRemove, nil
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
@@ -154,6 +155,51 @@ func (a *Api) generateTokens(ctx context.Context, id int64) (*tokens, error) {
 	}, nil
 }
 
+// publicURL returns the base URL the server is reachable at, request host is used if it is not configured.
+func publicURL(r *http.Request) string {
+	if u := config.PublicURL(); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+func etag(version int64) string {
+	return fmt.Sprintf("%q", strconv.FormatInt(version, 10))
+}
+
+var errMissingIfMatch = errors.New("If-Match header with the version of the resource is required")
+
+// readIfMatch returns the version from If-Match header, it is required to change the resource,
+// so that concurrent edits are not overwritten.
+func readIfMatch(r *http.Request) (int64, error) {
+	v := r.Header.Get("If-Match")
+	if v == "" || v == "*" {
+		return 0, errMissingIfMatch
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header %v", v)
+	}
+
+	return version, nil
+}
+
+// ifMatchErrorResponse responds 428 to requests without If-Match and 400 to invalid headers.
+func (a *Api) ifMatchErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errMissingIfMatch) {
+		a.preconditionRequiredResponse(w, r)
+		return
+	}
+	a.badRequestResponse(w, r, err)
+}
+
 func mapSlice[A any, B any](from []A, mapFn func(A) (B, error)) ([]B, error) {
 	res := make([]B, len(from))
 	for i, el := range from {
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/icalendar"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+)
+
+type importItemResp struct {
+	UID    string `json:"uid"`
+	Title  string `json:"title"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type importResp struct {
+	Imported    []*importItemResp `json:"imported"`
+	Skipped     []*importItemResp `json:"skipped"`
+	Unsupported []*importItemResp `json:"unsupported"`
+}
+
+// importEventsHandler creates events from the uploaded iCalendar file in the group.
+// Events that were already imported to the group are matched by UID and skipped.
+func (a *Api) importEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]model.GroupRole)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUserGroups)
+		return
+	}
+
+	multipartFile, headers, err := r.FormFile("file")
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+	defer multipartFile.Close()
+
+	if headers.Size > config.MaxFileSize() {
+		a.fileTooBigResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	groupID, err := strconv.ParseInt(r.FormValue("group_id"), 10, 64)
+	v.Check(err == nil, "group_id", "group_id must be provided")
+	role, ok := userGroups[groupID]
+	v.Check(err != nil || ok, "group_id", "user does not have access to group")
+	v.Check(err != nil || !ok || hasRole(role, editorRoles), "group_id", "user can't edit events of the group")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := a.users.GetUserByID(r.Context(), a.db, userID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get user: %w", err))
+		return
+	}
+
+	loc, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	events, items, err := icalendar.Decode(multipartFile, icalendar.DecodeOptions{
+		Location:          loc,
+		SupportedReminder: validNotifyOffset,
+	})
+	if err != nil {
+		a.badRequestResponse(w, r, fmt.Errorf("invalid calendar file: %w", err))
+		return
+	}
+
+	imported, err := a.eventsService.ImportEvents(r.Context(), userID, groupID, events)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("import events: %w", err))
+		return
+	}
+
+	resp := &importResp{
+		Imported:    []*importItemResp{},
+		Skipped:     []*importItemResp{},
+		Unsupported: []*importItemResp{},
+	}
+
+	for _, item := range append(imported, items...) {
+		itemResp := &importItemResp{
+			UID:    item.UID,
+			Title:  item.Title,
+			Reason: item.Reason,
+		}
+
+		switch item.Status {
+		case model.ImportStatusImported:
+			resp.Imported = append(resp.Imported, itemResp)
+		case model.ImportStatusSkipped:
+			resp.Skipped = append(resp.Skipped, itemResp)
+		default:
+			resp.Unsupported = append(resp.Unsupported, itemResp)
+		}
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
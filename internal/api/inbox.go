@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+)
+
+const (
+	defaultInboxLimit = 50
+	maxInboxLimit     = 200
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// inboxItemResp links to the event with the instance id, the same as in eventResp, if the item is about an event.
+type inboxItemResp struct {
+	ID        int64             `json:"id"`
+	Kind      string            `json:"kind"`
+	GroupID   int64             `json:"group_id"`
+	EventID   string            `json:"event_id"`
+	Data      map[string]string `json:"data"`
+	Read      bool              `json:"read"`
+	CreatedAt dateTime          `json:"created_at"`
+}
+
+var notificationKinds = map[model.NotificationKind]string{
+	model.NotificationKindReminder: "reminder",
+	model.NotificationKindChange:   "change",
+	model.NotificationKindDigest:   "digest",
+}
+
+// getInboxHandler returns items of the user from the newest one. Empty next cursor means there are no more items.
+func (a *Api) getInboxHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	beforeID, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	limit := uint64(defaultInboxLimit)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.ParseUint(v, 10, 64)
+		if err != nil || limit < 1 || limit > maxInboxLimit {
+			a.badRequestResponse(w, r, errors.New("limit must be valid"))
+			return
+		}
+	}
+
+	items, err := a.inbox.GetInboxItems(r.Context(), a.db, model.InboxFilter{
+		UserID:   userID,
+		BeforeID: beforeID,
+		Limit:    limit,
+	})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get inbox items: %w", err))
+		return
+	}
+
+	itemsResp, _ := mapSlice(items, func(i *model.InboxItem) (*inboxItemResp, error) {
+		return &inboxItemResp{
+			ID:        i.ID,
+			Kind:      notificationKinds[i.Kind],
+			GroupID:   i.GroupID,
+			EventID:   i.EventID,
+			Data:      i.Data,
+			Read:      i.ReadAt != nil,
+			CreatedAt: dateTime(i.CreatedAt),
+		}, nil
+	})
+
+	resp := &struct {
+		Items      []*inboxItemResp `json:"items"`
+		NextCursor string           `json:"next_cursor"`
+	}{
+		Items: itemsResp,
+	}
+	if uint64(len(items)) == limit {
+		resp.NextCursor = encodeCursor(items[len(items)-1].ID)
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *Api) getUnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	count, err := a.inbox.CountUnread(r.Context(), a.db, userID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("count unread: %w", err))
+		return
+	}
+
+	resp := &struct {
+		Count int64 `json:"count"`
+	}{
+		Count: count,
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *Api) markInboxReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	req := &struct {
+		IDs []int64 `json:"ids"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(req.IDs) != 0, "ids", "ids must be provided")
+	v.Check(len(req.IDs) <= maxInboxLimit, "ids", fmt.Sprintf("must not contain more than %v ids", maxInboxLimit))
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := a.inbox.MarkRead(r.Context(), a.db, userID, req.IDs); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("mark read: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *Api) markInboxAllReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	if err := a.inbox.MarkAllRead(r.Context(), a.db, userID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("mark all read: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// decodeCursor returns zero id for the empty cursor, that means the first page.
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errInvalidCursor
+	}
+
+	return id, nil
+}
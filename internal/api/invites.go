@@ -0,0 +1,546 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// inviteResp describes the pending invite to admins of the group. Code of the invite link
+// is returned only once, when the link is created.
+type inviteResp struct {
+	ID        int64           `json:"id"`
+	GroupID   int64           `json:"group_id"`
+	GroupName string          `json:"group_name,omitempty"`
+	InviterID int64           `json:"inviter_id"`
+	UserID    int64           `json:"user_id,omitempty"`
+	Link      bool            `json:"link"`
+	Code      string          `json:"code,omitempty"`
+	Role      model.GroupRole `json:"role"`
+	ExpiresAt dateTime        `json:"expires_at"`
+	CreatedAt dateTime        `json:"created_at"`
+}
+
+func (a *Api) getGroupInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	invites, err := a.invites.GetInvites(r.Context(), a.db, model.InvitesFilter{GroupIDs: []int64{group.ID}})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get invites: %w", err))
+		return
+	}
+
+	resp, _ := mapSlice(invites, func(i *model.GroupInvite) (*inviteResp, error) {
+		return mapToInviteResp(i), nil
+	})
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// createGroupInviteHandler invites the user to the group, the user joins it only after accepting.
+func (a *Api) createGroupInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	role, ok := r.Context().Value(contextKeyRole).(model.GroupRole)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveRole)
+		return
+	}
+
+	req := &struct {
+		UserID int64           `json:"user_id"`
+		Role   model.GroupRole `json:"role"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = model.GroupRoleEditor
+	}
+
+	v := validator.New()
+	v.Check(req.UserID != 0, "user_id", "user_id must be provided")
+	_, member := group.Roles[req.UserID]
+	v.Check(!member, "user_id", "user is already a member of the group")
+	validateInviteRole(v, req.Role, role)
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if _, err := a.users.GetUserByID(r.Context(), a.db, req.UserID); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			v.AddError("user_id", "user does not exist")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("get user: %w", err))
+		}
+		return
+	}
+
+	invite := &model.GroupInvite{
+		GroupID:   group.ID,
+		InviterID: userID,
+		UserID:    req.UserID,
+		Role:      req.Role,
+		ExpiresAt: time.Now().Add(config.InviteTTL()),
+	}
+
+	if err := a.invites.DeleteExpiredInvites(r.Context(), a.db, group.ID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete expired invites: %w", err))
+		return
+	}
+
+	id, err := a.invites.CreateInvite(r.Context(), a.db, invite)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("create invite: %w", err))
+		return
+	}
+	invite.ID = id
+	invite.CreatedAt = time.Now()
+
+	if err := a.writeJSON(w, http.StatusCreated, mapToInviteResp(invite), nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// createInviteLinkHandler generates the code, with which any signed-in user can join the group
+// until it expires or is cancelled.
+func (a *Api) createInviteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	role, ok := r.Context().Value(contextKeyRole).(model.GroupRole)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveRole)
+		return
+	}
+
+	req := &struct {
+		Role      model.GroupRole `json:"role"`
+		ExpiresIn duration        `json:"expires_in"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = model.GroupRoleEditor
+	}
+
+	ttl := time.Duration(req.ExpiresIn)
+	if ttl == 0 {
+		ttl = config.InviteTTL()
+	}
+
+	v := validator.New()
+	validateInviteRole(v, req.Role, role)
+	v.Check(ttl >= time.Minute && ttl <= config.MaxInviteLinkTTL(), "expires_in", fmt.Sprintf("must be from 1 minute up to %v", config.MaxInviteLinkTTL()))
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := a.invites.DeleteExpiredInvites(r.Context(), a.db, group.ID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete expired invites: %w", err))
+		return
+	}
+
+	code, invite, err := a.generateInviteLink(r.Context(), &model.GroupInvite{
+		GroupID:   group.ID,
+		InviterID: userID,
+		Role:      req.Role,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("generate invite link: %w", err))
+		return
+	}
+
+	resp := mapToInviteResp(invite)
+	resp.Code = code
+
+	if err := a.writeJSON(w, http.StatusCreated, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// cancelGroupInviteHandler cancels the personal invite or revokes the invite link.
+func (a *Api) cancelGroupInviteHandler(w http.ResponseWriter, r *http.Request) {
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	if _, err := a.invites.TakeInvite(r.Context(), a.db, id, group.ID, 0); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("take invite: %w", err))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// getUserInvitesHandler returns pending personal invites of the user.
+func (a *Api) getUserInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	invites, err := a.invites.GetInvites(r.Context(), a.db, model.InvitesFilter{UserIDs: []int64{userID}})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get invites: %w", err))
+		return
+	}
+
+	groupIDs := make([]int64, len(invites))
+	for i, inv := range invites {
+		groupIDs[i] = inv.GroupID
+	}
+
+	names := make(map[int64]string, len(invites))
+	if len(groupIDs) != 0 {
+		groups, err := a.groups.GetGroups(r.Context(), a.db, groupIDs)
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get groups: %w", err))
+			return
+		}
+
+		for _, g := range groups {
+			names[g.ID] = g.Name
+		}
+	}
+
+	resp, _ := mapSlice(invites, func(i *model.GroupInvite) (*inviteResp, error) {
+		res := mapToInviteResp(i)
+		res.GroupName = names[i.GroupID]
+		return res, nil
+	})
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *Api) acceptInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	tx, err := a.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("begin tx: %w", err))
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	invite, err := a.invites.TakeInvite(r.Context(), tx, id, 0, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("take invite: %w", err))
+		}
+		return
+	}
+
+	if err := a.joinGroup(r.Context(), tx, invite, userID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("join group: %w", err))
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("commit tx: %w", err))
+		return
+	}
+
+	a.writeGroupID(w, r, invite.GroupID)
+}
+
+func (a *Api) declineInviteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	if _, err := a.invites.TakeInvite(r.Context(), a.db, id, 0, userID); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("take invite: %w", err))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// joinGroupHandler adds the user to the group of the invite link, the link stays valid for others.
+func (a *Api) joinGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	req := &struct {
+		Code string `json:"code"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(req.Code) != 0, "code", "code must be provided")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	invite, err := a.invites.GetInviteByCodeHash(r.Context(), a.db, hashToken(req.Code))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("get invite: %w", err))
+		}
+		return
+	}
+
+	tx, err := a.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("begin tx: %w", err))
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	if err := a.joinGroup(r.Context(), tx, invite, userID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("join group: %w", err))
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("commit tx: %w", err))
+		return
+	}
+
+	a.writeGroupID(w, r, invite.GroupID)
+}
+
+// joinGroup adds the user to the group of the invite with its role and notifies other members.
+// Members joining again keep their role.
+func (a *Api) joinGroup(ctx context.Context, tx database.Queryable, invite *model.GroupInvite, userID int64) error {
+	if _, err := a.groups.LockGroup(ctx, tx, invite.GroupID); err != nil {
+		return fmt.Errorf("lock group: %w", err)
+	}
+
+	group, err := a.groups.GetGroup(ctx, tx, invite.GroupID)
+	if err != nil {
+		return fmt.Errorf("get group: %w", err)
+	}
+
+	if _, ok := group.Roles[userID]; ok {
+		return nil
+	}
+
+	settings, err := a.groups.GetUserGroupSettings(ctx, tx, model.UserGroupSettingsFilter{
+		UserIDs:  []int64{group.CreatorID},
+		GroupIDs: []int64{group.ID},
+	})
+	if err != nil {
+		return fmt.Errorf("get group settings: %w", err)
+	}
+	if len(settings) != 1 {
+		return fmt.Errorf("invalid number of group settings %d", len(settings))
+	}
+
+	if err := a.groups.AddUserToGroup(ctx, tx, &model.GroupSettings{
+		UserID:  userID,
+		GroupID: group.ID,
+		Color:   settings[0].Color,
+		Notify:  true,
+		Role:    invite.Role,
+	}); err != nil {
+		return fmt.Errorf("add user to group: %w", err)
+	}
+
+	if err := a.invites.DeleteUserInvites(ctx, tx, group.ID, userID); err != nil {
+		return fmt.Errorf("delete user invites: %w", err)
+	}
+
+	if err := a.groups.IncrementGroupVersion(ctx, tx, group.ID); err != nil {
+		return fmt.Errorf("increment group version: %w", err)
+	}
+
+	if err := a.notifyGroup(ctx, tx, &model.GroupChange{
+		GroupID:   group.ID,
+		ActorID:   userID,
+		Type:      model.ChangeTypeMembersAdded,
+		MemberIDs: []int64{userID},
+	}); err != nil {
+		return fmt.Errorf("notify group: %w", err)
+	}
+
+	return nil
+}
+
+// inviteUsers creates personal invites instead of adding users to the group directly,
+// users without a role in roles are invited as editors.
+func (a *Api) inviteUsers(ctx context.Context, q database.Queryable, groupID int64, inviterID int64, ids []int64, roles map[int64]model.GroupRole) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := a.invites.DeleteExpiredInvites(ctx, q, groupID); err != nil {
+		return fmt.Errorf("delete expired invites: %w", err)
+	}
+
+	expiresAt := time.Now().Add(config.InviteTTL())
+	for _, id := range ids {
+		role, ok := roles[id]
+		if !ok {
+			role = model.GroupRoleEditor
+		}
+
+		if _, err := a.invites.CreateInvite(ctx, q, &model.GroupInvite{
+			GroupID:   groupID,
+			InviterID: inviterID,
+			UserID:    id,
+			Role:      role,
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			return fmt.Errorf("create invite: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (a *Api) generateInviteLink(ctx context.Context, invite *model.GroupInvite) (string, *model.GroupInvite, error) {
+	for {
+		code, err := a.generateRandomString(config.InviteCodeLength())
+		if err != nil {
+			return "", nil, err
+		}
+
+		invite.CodeHash = hashToken(code)
+		invite.ID, err = a.invites.CreateInvite(ctx, a.db, invite)
+		if err != nil {
+			if errors.Is(err, model.ErrAlreadyExists) {
+				continue
+			}
+			return "", nil, err
+		}
+		invite.CreatedAt = time.Now()
+
+		return code, invite, nil
+	}
+}
+
+func (a *Api) writeGroupID(w http.ResponseWriter, r *http.Request, groupID int64) {
+	resp := &struct {
+		GroupID int64 `json:"group_id"`
+	}{
+		GroupID: groupID,
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateInviteRole checks that the member with the role can invite others with the invited role.
+func validateInviteRole(v *validator.Validator, invited model.GroupRole, role model.GroupRole) {
+	v.Check(hasRole(invited, groupRoles), "role", fmt.Sprintf("unknown role %q", invited))
+	v.Check(invited != model.GroupRoleOwner, "role", "owner role can't be granted")
+	v.Check(invited != model.GroupRoleAdmin || role == model.GroupRoleOwner, "role", "only owner can grant admin role")
+}
+
+func mapToInviteResp(i *model.GroupInvite) *inviteResp {
+	return &inviteResp{
+		ID:        i.ID,
+		GroupID:   i.GroupID,
+		InviterID: i.InviterID,
+		UserID:    i.UserID,
+		Link:      i.UserID == 0,
+		Role:      i.Role,
+		ExpiresAt: dateTime(i.ExpiresAt),
+		CreatedAt: dateTime(i.CreatedAt),
+	}
+}
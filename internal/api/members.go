@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+)
+
+var (
+	groupRoles = []model.GroupRole{model.GroupRoleOwner, model.GroupRoleAdmin, model.GroupRoleEditor, model.GroupRoleViewer}
+	// editorRoles can change events of the group, viewers can only read them.
+	editorRoles = []model.GroupRole{model.GroupRoleOwner, model.GroupRoleAdmin, model.GroupRoleEditor}
+	// managerRoles can change the group, its members and their roles.
+	managerRoles = []model.GroupRole{model.GroupRoleOwner, model.GroupRoleAdmin}
+)
+
+func hasRole(role model.GroupRole, roles []model.GroupRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type memberReq struct {
+	UserID int64           `json:"user_id"`
+	Role   model.GroupRole `json:"role"`
+}
+
+// updateMembersHandler replaces members of the group with their roles, new users are invited.
+// The owner can't be removed or changed, only the owner can grant, revoke or remove the admin role.
+func (a *Api) updateMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	group, ok := r.Context().Value(contextKeyGroup).(*model.Group)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveGroup)
+		return
+	}
+
+	role, ok := r.Context().Value(contextKeyRole).(model.GroupRole)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveRole)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		a.ifMatchErrorResponse(w, r, err)
+		return
+	}
+
+	if version != group.Version {
+		a.editConflictResponse(w, r)
+		return
+	}
+
+	req := &struct {
+		Members []*memberReq `json:"members"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	roles := make(map[int64]model.GroupRole, len(req.Members))
+	ids := make([]int64, 0, len(req.Members))
+	for _, m := range req.Members {
+		_, ok := roles[m.UserID]
+		v.Check(!ok, "members", fmt.Sprintf("user %d is listed twice", m.UserID))
+		v.Check(hasRole(m.Role, groupRoles), "members", fmt.Sprintf("unknown role %q", m.Role))
+		roles[m.UserID] = m.Role
+		ids = append(ids, m.UserID)
+	}
+
+	toAdd, toRemove, err := calculateUsers(group, ids, role)
+	if err != nil {
+		v.AddError("members", err.Error())
+	}
+
+	changed, err := calculateRoles(group, roles, role)
+	if err != nil {
+		v.AddError("members", err.Error())
+	}
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tx, err := a.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("begin tx: %w", err))
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	// members and roles were calculated from the group read before, so it must not change until commit
+	currentVersion, err := a.groups.LockGroup(r.Context(), tx, group.ID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("lock group: %w", err))
+		return
+	}
+
+	if currentVersion != group.Version {
+		a.editConflictResponse(w, r)
+		return
+	}
+
+	if err := a.groups.IncrementGroupVersion(r.Context(), tx, group.ID); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("increment group version: %w", err))
+		return
+	}
+
+	// new users join the group with their roles only after accepting the invite
+	if err := a.inviteUsers(r.Context(), tx, group.ID, userID, toAdd, roles); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("invite users: %w", err))
+		return
+	}
+
+	for _, id := range changed {
+		if err := a.groups.UpdateUserRole(r.Context(), tx, group.ID, id, roles[id]); err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("update user role: %w", err))
+			return
+		}
+	}
+
+	var changes []*model.GroupChange
+	if len(changed) != 0 {
+		changes = append(changes, &model.GroupChange{
+			GroupID:   group.ID,
+			ActorID:   userID,
+			Type:      model.ChangeTypeRolesChanged,
+			MemberIDs: changed,
+		})
+	}
+	// removed members are notified too, so they are notified before the removal
+	if len(toRemove) != 0 {
+		changes = append(changes, &model.GroupChange{
+			GroupID:   group.ID,
+			ActorID:   userID,
+			Type:      model.ChangeTypeMembersRemoved,
+			MemberIDs: toRemove,
+		})
+	}
+
+	for _, c := range changes {
+		if err := a.notifyGroup(r.Context(), tx, c); err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("notify group: %w", err))
+			return
+		}
+	}
+
+	for _, id := range toRemove {
+		if err := a.groups.RemoveUserFromGroup(r.Context(), tx, group.ID, id); err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("remove user from group: %w", err))
+			return
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("commit tx: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// calculateRoles returns current members, whose role changes, role is the role of the member
+// making the change. The owner role is neither granted nor changed, the admin role is managed only by the owner.
+func calculateRoles(group *model.Group, newRoles map[int64]model.GroupRole, role model.GroupRole) ([]int64, error) {
+	var changed []int64
+	for _, id := range group.UsersIDs {
+		newRole, ok := newRoles[id]
+		oldRole := group.Roles[id]
+		if !ok || newRole == oldRole {
+			continue
+		}
+
+		if oldRole == model.GroupRoleOwner {
+			return nil, fmt.Errorf("can't change role of owner")
+		}
+
+		if newRole == model.GroupRoleOwner {
+			return nil, fmt.Errorf("owner role can't be granted")
+		}
+
+		if (oldRole == model.GroupRoleAdmin || newRole == model.GroupRoleAdmin) && role != model.GroupRoleOwner {
+			return nil, fmt.Errorf("only owner can grant or revoke admin role")
+		}
+
+		changed = append(changed, id)
+	}
+
+	for id, newRole := range newRoles {
+		if _, ok := group.Roles[id]; ok {
+			continue
+		}
+
+		if newRole == model.GroupRoleOwner {
+			return nil, fmt.Errorf("owner role can't be granted")
+		}
+
+		if newRole == model.GroupRoleAdmin && role != model.GroupRoleOwner {
+			return nil, fmt.Errorf("only owner can grant or revoke admin role")
+		}
+	}
+
+	return changed, nil
+}
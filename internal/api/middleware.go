@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/jwt"
 	"github.com/go-chi/chi/v5"
@@ -22,9 +23,12 @@ const (
 	contextKeyGroup      = contextKey("group")
 	contextKeyUserGroups = contextKey("user_groups")
 	contextKeyEvent      = contextKey("event")
+	contextKeyRole       = contextKey("role")
+	contextKeyIfMatch    = contextKey("if_match")
 )
 
 var errCantRetrieveID = errors.New("can't retrieve id")
+var errInvalidCredentials = errors.New("invalid email or app password")
 
 func (a *Api) auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -53,6 +57,56 @@ func (a *Api) auth(next http.Handler) http.Handler {
 	})
 }
 
+// davAuth authenticates DAV clients with Basic auth by the user email and app password,
+// since they can't go through Google sign-in.
+func (a *Api) davAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unauthorized := func(err error) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Shared Planner", charset="UTF-8"`)
+			a.unauthorizedResponse(w, r, err)
+		}
+
+		email, password, ok := r.BasicAuth()
+		if !ok {
+			unauthorized(errors.New("no credentials provided"))
+			return
+		}
+
+		appPassword, err := a.passwords.GetAppPasswordByHash(r.Context(), a.db, hashToken(password))
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrNoRecord):
+				unauthorized(errInvalidCredentials)
+			default:
+				a.serverErrorResponse(w, r, fmt.Errorf("get app password: %w", err))
+			}
+			return
+		}
+
+		user, err := a.users.GetUserByID(r.Context(), a.db, appPassword.UserID)
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get user: %w", err))
+			return
+		}
+
+		if !strings.EqualFold(user.Email, email) {
+			unauthorized(errInvalidCredentials)
+			return
+		}
+
+		// last use is shown to the user only roughly, so it isn't written on every request
+		if appPassword.LastUsedAt == nil || time.Since(*appPassword.LastUsedAt) > config.AppPasswordTouchTTL() {
+			if err := a.passwords.TouchAppPassword(r.Context(), a.db, appPassword.ID); err != nil {
+				a.serverErrorResponse(w, r, fmt.Errorf("touch app password: %w", err))
+				return
+			}
+		}
+
+		idContext := context.WithValue(r.Context(), contextKeyID, user.ID)
+		next.ServeHTTP(w, r.WithContext(idContext))
+	})
+}
+
 func (a *Api) userCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		id, ok := r.Context().Value(contextKeyID).(int64)
@@ -102,20 +156,14 @@ func (a *Api) groupCtx(next http.Handler) http.Handler {
 			return
 		}
 
-		userInGroup := false
-		for _, id := range group.UsersIDs {
-			if id == userID {
-				userInGroup = true
-				break
-			}
-		}
-
-		if !userInGroup {
+		role, ok := group.Roles[userID]
+		if !ok {
 			a.notFoundResponse(w, r)
 			return
 		}
 
 		groupCtx := context.WithValue(r.Context(), contextKeyGroup, group)
+		groupCtx = context.WithValue(groupCtx, contextKeyRole, role)
 		next.ServeHTTP(w, r.WithContext(groupCtx))
 	})
 }
@@ -134,10 +182,10 @@ func (a *Api) userGroupsCtx(next http.Handler) http.Handler {
 			return
 		}
 
-		groupsMap := make(map[int64]struct{}, len(groups))
+		groupsMap := make(map[int64]model.GroupRole, len(groups))
 
 		for _, g := range groups {
-			groupsMap[g.ID] = struct{}{}
+			groupsMap[g.ID] = g.Roles[userID]
 		}
 
 		groupCtx := context.WithValue(r.Context(), contextKeyUserGroups, groupsMap)
@@ -147,7 +195,7 @@ func (a *Api) userGroupsCtx(next http.Handler) http.Handler {
 
 func (a *Api) eventCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]struct{})
+		userGroups, ok := r.Context().Value(contextKeyUserGroups).(map[int64]model.GroupRole)
 		if !ok {
 			a.serverErrorResponse(w, r, errCantRetrieveUserGroups)
 			return
@@ -170,16 +218,39 @@ func (a *Api) eventCtx(next http.Handler) http.Handler {
 			return
 		}
 
-		if _, ok := userGroups[event.GroupID]; !ok {
+		role, ok := userGroups[event.GroupID]
+		if !ok {
 			a.notFoundResponse(w, r)
 			return
 		}
 
 		eventCtx := context.WithValue(r.Context(), contextKeyEvent, event)
+		eventCtx = context.WithValue(eventCtx, contextKeyRole, role)
 		next.ServeHTTP(w, r.WithContext(eventCtx))
 	})
 }
 
+// requireRole allows the request only to members with one of the roles in the group
+// put into the context by groupCtx or eventCtx.
+func (a *Api) requireRole(roles []model.GroupRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value(contextKeyRole).(model.GroupRole)
+			if !ok {
+				a.serverErrorResponse(w, r, errCantRetrieveRole)
+				return
+			}
+
+			if !hasRole(role, roles) {
+				a.forbiddenResponse(w, r, fmt.Sprintf("not allowed for %s role", role))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func splitID(fullID string) (int64, time.Time, error) {
 	parts := strings.Split(fullID, "_")
 	if len(parts) != 2 {
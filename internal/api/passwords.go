@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+type appPasswordResp struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Password   string    `json:"password,omitempty"`
+	CreatedAt  dateTime  `json:"created_at"`
+	LastUsedAt *dateTime `json:"last_used_at"`
+}
+
+func (a *Api) getAppPasswordsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	passwords, err := a.passwords.GetUserAppPasswords(r.Context(), a.db, userID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get app passwords: %w", err))
+		return
+	}
+
+	resp, _ := mapSlice(passwords, func(p *model.AppPassword) (*appPasswordResp, error) {
+		return mapToAppPasswordResp(p), nil
+	})
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// createAppPasswordHandler generates a password for DAV clients, it is returned only once.
+func (a *Api) createAppPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	req := &struct {
+		Name string `json:"name"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(req.Name) != 0, "name", "name must be provided")
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	password, appPassword, err := a.generateAppPassword(r.Context(), userID, req.Name)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("generate app password: %w", err))
+		return
+	}
+
+	resp := mapToAppPasswordResp(appPassword)
+	resp.Password = password
+
+	if err := a.writeJSON(w, http.StatusCreated, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *Api) deleteAppPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "passwordID"), 10, 64)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	if err := a.passwords.DeleteAppPassword(r.Context(), a.db, userID, id); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("delete app password: %w", err))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a *Api) generateAppPassword(ctx context.Context, userID int64, name string) (string, *model.AppPassword, error) {
+	for {
+		password, err := a.generateRandomString(config.AppPasswordLength())
+		if err != nil {
+			return "", nil, err
+		}
+
+		appPassword, err := a.passwords.CreateAppPassword(ctx, a.db, &model.AppPassword{
+			UserID: userID,
+			Name:   name,
+			Hash:   hashToken(password),
+		})
+		if err != nil {
+			if errors.Is(err, model.ErrAlreadyExists) {
+				continue
+			}
+			return "", nil, err
+		}
+
+		return password, appPassword, nil
+	}
+}
+
+// hashToken doesn't need salt, since app passwords and session tokens are random and long enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func mapToAppPasswordResp(p *model.AppPassword) *appPasswordResp {
+	resp := &appPasswordResp{
+		ID:        p.ID,
+		Name:      p.Name,
+		CreatedAt: dateTime(p.CreatedAt),
+	}
+	if p.LastUsedAt != nil {
+		lastUsedAt := dateTime(*p.LastUsedAt)
+		resp.LastUsedAt = &lastUsedAt
+	}
+
+	return resp
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+)
+
+type quietHoursResp struct {
+	Enabled     bool              `json:"enabled"`
+	Start       string            `json:"start"`
+	End         string            `json:"end"`
+	Policy      model.QuietPolicy `json:"policy"`
+	SnoozeUntil *dateTime         `json:"snooze_until"`
+}
+
+func (a *Api) getQuietHoursHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	resp := &quietHoursResp{
+		Enabled: user.Quiet.Enabled,
+		Start:   formatMinutes(user.Quiet.Start),
+		End:     formatMinutes(user.Quiet.End),
+		Policy:  user.Quiet.Policy,
+	}
+
+	// past snooze is reported as no snooze
+	if user.Quiet.SnoozeUntil.After(time.Now()) {
+		until := dateTime(user.Quiet.SnoozeUntil)
+		resp.SnoozeUntil = &until
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateQuietHoursHandler sets quiet hours of the user, times are local to the time zone of the user.
+// The policy applies to the snooze too.
+func (a *Api) updateQuietHoursHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	req := &struct {
+		Enabled bool              `json:"enabled"`
+		Start   string            `json:"start"`
+		End     string            `json:"end"`
+		Policy  model.QuietPolicy `json:"policy"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	start, err := parseMinutes(req.Start)
+	v.Check(err == nil, "start", "must be time in HH:MM format")
+	end, err := parseMinutes(req.End)
+	v.Check(err == nil, "end", "must be time in HH:MM format")
+	v.Check(validator.In(string(req.Policy), string(model.QuietPolicyDefer), string(model.QuietPolicyDrop)), "policy", "must be defer or drop")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	quiet := &model.QuietHours{
+		Enabled: req.Enabled,
+		Start:   start,
+		End:     end,
+		Policy:  req.Policy,
+	}
+
+	if err := a.users.UpdateQuietHours(r.Context(), a.db, user.ID, quiet); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("update quiet hours: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// updateSnoozeHandler silences all notifications of the user until the given time, null cancels the snooze.
+func (a *Api) updateSnoozeHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	req := &struct {
+		Until *dateTime `json:"until"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	var until time.Time
+	if req.Until != nil {
+		until = time.Time(*req.Until)
+	}
+
+	v := validator.New()
+	v.Check(until.IsZero() || until.After(time.Now()), "until", "must be in the future")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := a.users.UpdateSnoozeUntil(r.Context(), a.db, user.ID, until); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("update snooze: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
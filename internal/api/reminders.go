@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSnooze limits how far the sent reminder can be put off.
+const maxSnooze = 24 * time.Hour
+
+// remindersResp always contains reminders the user gets, event defaults are returned unless custom is set.
+type remindersResp struct {
+	Custom        bool               `json:"custom"`
+	Notifications []notificationType `json:"notifications"`
+	NotifyOffsets []duration         `json:"notify_offsets"`
+	NotifyAt      []dateTime         `json:"notify_at"`
+	Muted         bool               `json:"muted"`
+}
+
+func (a *Api) getRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	event, ok := r.Context().Value(contextKeyEvent).(*model.Event)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveEvent)
+		return
+	}
+
+	id, _, err := splitID(event.ID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", err))
+		return
+	}
+
+	reminders, err := a.reminders.GetReminders(r.Context(), a.db, model.RemindersFilter{
+		UserIDs:  []int64{userID},
+		EventIDs: []int64{id},
+	})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get reminders: %w", err))
+		return
+	}
+
+	resp := &remindersResp{
+		Notifications: mapToNotificationTypesResp(event.Notifications),
+		NotifyOffsets: mapToDurationsResp(event.Notifications),
+		NotifyAt:      mapToDateTimesResp(event.NotifyAt),
+	}
+	if len(reminders) != 0 {
+		resp.Muted = reminders[0].Muted
+		if reminders[0].Custom {
+			resp.Custom = true
+			resp.Notifications = mapToNotificationTypesResp(reminders[0].Notifications)
+			resp.NotifyOffsets = mapToDurationsResp(reminders[0].Notifications)
+			resp.NotifyAt = mapToDateTimesResp(reminders[0].NotifyAt)
+		}
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateRemindersHandler sets personal reminders of the user for the whole series. Without custom
+// the event defaults are used, so that only muting can be changed.
+func (a *Api) updateRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	event, ok := r.Context().Value(contextKeyEvent).(*model.Event)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveEvent)
+		return
+	}
+
+	req := &struct {
+		Custom        bool               `json:"custom"`
+		Notifications []notificationType `json:"notifications"`
+		NotifyOffsets []duration         `json:"notify_offsets"`
+		NotifyAt      []dateTime         `json:"notify_at"`
+		Muted         bool               `json:"muted"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(req.Custom || len(req.Notifications) == 0 && len(req.NotifyOffsets) == 0 && len(req.NotifyAt) == 0, "custom", "custom must be set to change reminders")
+	validateNotifications(v, req.NotifyOffsets, req.Notifications, req.NotifyAt)
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	id, _, err := splitID(event.ID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", err))
+		return
+	}
+
+	reminders, err := a.reminders.GetReminders(r.Context(), a.db, model.RemindersFilter{
+		UserIDs:  []int64{userID},
+		EventIDs: []int64{id},
+	})
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get reminders: %w", err))
+		return
+	}
+
+	// reminders set by new clients are kept, if old clients change only legacy types
+	var storedOffsets []time.Duration
+	var storedTimes []time.Time
+	if req.Custom && len(reminders) != 0 && reminders[0].Custom {
+		storedOffsets = reminders[0].Notifications
+		storedTimes = reminders[0].NotifyAt
+	}
+
+	if err := a.reminders.UpsertReminders(r.Context(), a.db, &model.Reminders{
+		UserID:        userID,
+		EventID:       id,
+		Custom:        req.Custom,
+		Notifications: mergeNotifyOffsets(storedOffsets, req.NotifyOffsets, req.Notifications),
+		NotifyAt:      mergeTimes(storedTimes, req.NotifyAt),
+		Muted:         req.Muted,
+	}); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("upsert reminders: %w", err))
+		return
+	}
+
+	// personal offsets are scheduled as well as the event defaults
+	if err := a.eventsService.RefreshSchedules(r.Context(), []int64{id}, time.Now()); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("refresh schedules: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// resetRemindersHandler returns the event defaults and unmutes the event.
+func (a *Api) resetRemindersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	event, ok := r.Context().Value(contextKeyEvent).(*model.Event)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveEvent)
+		return
+	}
+
+	id, _, err := splitID(event.ID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("split id: %w", err))
+		return
+	}
+
+	if err := a.reminders.DeleteReminders(r.Context(), a.db, userID, id); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("delete reminders: %w", err))
+		return
+	}
+
+	if err := a.eventsService.RefreshSchedules(r.Context(), []int64{id}, time.Now()); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("refresh schedules: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// snoozeReminderHandler sends the reminder again after the given duration, reminder id comes
+// in the data of the sent reminder.
+func (a *Api) snoozeReminderHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "reminderID"), 10, 64)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	req := &struct {
+		Duration duration `json:"duration"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	d := time.Duration(req.Duration)
+
+	v := validator.New()
+	v.Check(d >= time.Minute && d <= maxSnooze && d%time.Minute == 0, "duration", fmt.Sprintf("must be whole minutes up to %v", maxSnooze))
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	sendAt, err := a.reminderActions.SnoozeReminder(r.Context(), userID, id, d)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("snooze reminder: %w", err))
+		}
+		return
+	}
+
+	resp := &struct {
+		SendAt dateTime `json:"send_at"`
+	}{
+		SendAt: dateTime(sendAt),
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// ackReminderHandler marks the occurrence of the reminder as done, so that remaining and snoozed
+// reminders for it are not sent.
+func (a *Api) ackReminderHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "reminderID"), 10, 64)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	if err := a.reminderActions.AckReminder(r.Context(), userID, id); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, fmt.Errorf("ack reminder: %w", err))
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
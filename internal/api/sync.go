@@ -0,0 +1,371 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+var errInvalidSyncToken = errors.New("invalid sync token")
+
+type overrideResp struct {
+	RecurrenceID  dateTime           `json:"recurrence_id"`
+	EventType     model.EventType    `json:"event_type"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	AllDay        bool               `json:"all_day"`
+	From          dateTime           `json:"from"`
+	To            dateTime           `json:"to"`
+	Notifications []notificationType `json:"notifications"`
+	NotifyOffsets []duration         `json:"notify_offsets"`
+	Attachments   []*attachment      `json:"attachments"`
+	UpdatedBy     int64              `json:"updated_by"`
+	UpdatedAt     dateTime           `json:"updated_at"`
+}
+
+// seriesResp is a stored event, instances of which are expanded by the client.
+// Instance ids are built the same way as in eventResp: "<id>_<unix start>".
+type seriesResp struct {
+	ID            string             `json:"id"`
+	GroupID       int64              `json:"group_id"`
+	EventType     model.EventType    `json:"event_type"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"`
+	AllDay        bool               `json:"all_day"`
+	From          dateTime           `json:"from"`
+	To            dateTime           `json:"to"`
+	TimeZone      string             `json:"time_zone"`
+	RepeatType    model.RepeatType   `json:"repeat_type"`
+	Recurrence    *recurrence        `json:"recurrence"`
+	Exceptions    []dateTime         `json:"exceptions"`
+	Overrides     []*overrideResp    `json:"overrides"`
+	Notifications []notificationType `json:"notifications"`
+	NotifyOffsets []duration         `json:"notify_offsets"`
+	NotifyAt      []dateTime         `json:"notify_at"`
+	Attachments   []*attachment      `json:"attachments"`
+	CreatorID     int64              `json:"creator_id"`
+	UpdatedBy     int64              `json:"updated_by"`
+	CreatedAt     dateTime           `json:"created_at"`
+	UpdatedAt     dateTime           `json:"updated_at"`
+	Version       int64              `json:"version"`
+}
+
+type syncGroupResp struct {
+	ID        int64                     `json:"id"`
+	Name      string                    `json:"name"`
+	CreatorID int64                     `json:"creator_id"`
+	Color     string                    `json:"color"`
+	Notify    bool                      `json:"notify"`
+	UsersIDs  []int64                   `json:"users_ids"`
+	Roles     map[int64]model.GroupRole `json:"roles"`
+	Version   int64                     `json:"version"`
+}
+
+type syncResp struct {
+	SyncToken     string           `json:"sync_token"`
+	FullSync      bool             `json:"full_sync"`
+	Events        []*seriesResp    `json:"events"`
+	DeletedEvents []string         `json:"deleted_events"`
+	Groups        []*syncGroupResp `json:"groups"`
+	DeletedGroups []int64          `json:"deleted_groups"`
+}
+
+// syncHandler returns events and groups changed since the sync token. Without the token
+// everything available to the user is returned. Events of the deleted groups are not
+// listed in deleted_events, clients should drop them together with the group.
+// Changes older than the retention are deleted, tokens issued before that get everything
+// as well, full_sync tells clients to replace their data instead of merging it.
+func (a *Api) syncHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextKeyID).(int64)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveID)
+		return
+	}
+
+	syncPoint, err := decodeSyncToken(r.URL.Query().Get("sync_token"))
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	// sync point is taken before reading anything, so that changes made concurrently are returned again next time
+	newSyncPoint, err := a.changes.GetSyncPoint(r.Context(), a.db)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get sync point: %w", err))
+		return
+	}
+
+	userGroups, err := a.groups.GetUserGroups(r.Context(), a.db, userID)
+	if err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("get groups: %w", err))
+		return
+	}
+
+	userGroupsMap := make(map[int64]struct{}, len(userGroups))
+	userGroupIDs := make([]int64, len(userGroups))
+	for i, g := range userGroups {
+		userGroupsMap[g.ID] = struct{}{}
+		userGroupIDs[i] = g.ID
+	}
+
+	changedEvents := newIDSet()
+	changedGroups := newIDSet()
+	joinedGroups := newIDSet()
+
+	fullSync := syncPoint == 0
+
+	var changes []*model.Change
+	if !fullSync {
+		changes, err = a.changes.GetChanges(r.Context(), a.db, model.ChangesFilter{
+			SyncPoint: syncPoint,
+			GroupIDs:  userGroupIDs,
+			UserID:    userID,
+		})
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get changes: %w", err))
+			return
+		}
+
+		// horizon is read after the changes, cleanup moves it before deleting anything
+		horizon, err := a.changes.GetHorizon(r.Context(), a.db)
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get horizon: %w", err))
+			return
+		}
+		fullSync = syncPoint <= horizon
+	}
+
+	if fullSync {
+		for _, id := range userGroupIDs {
+			changedGroups.add(id)
+			joinedGroups.add(id)
+		}
+	} else {
+		for _, c := range changes {
+			switch c.EntityType {
+			case model.EntityTypeEvent:
+				changedEvents.add(c.EntityID)
+			case model.EntityTypeGroup:
+				changedGroups.add(c.GroupID)
+			case model.EntityTypeMembership:
+				changedGroups.add(c.GroupID)
+				if _, ok := userGroupsMap[c.GroupID]; ok && c.UserID == userID {
+					joinedGroups.add(c.GroupID)
+				}
+			}
+		}
+	}
+
+	var series []*model.Series
+	if len(joinedGroups.ids) != 0 {
+		s, err := a.eventsService.GetSeries(r.Context(), model.EventsFilter{GroupIDs: joinedGroups.ids})
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get series of groups: %w", err))
+			return
+		}
+		series = append(series, s...)
+	}
+	if len(changedEvents.ids) != 0 {
+		s, err := a.eventsService.GetSeries(r.Context(), model.EventsFilter{IDs: changedEvents.ids})
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get changed series: %w", err))
+			return
+		}
+		series = append(series, s...)
+	}
+
+	resp := &syncResp{
+		SyncToken:     encodeSyncToken(newSyncPoint),
+		FullSync:      fullSync,
+		Events:        []*seriesResp{},
+		DeletedEvents: []string{},
+		Groups:        []*syncGroupResp{},
+		DeletedGroups: []int64{},
+	}
+
+	found := make(map[string]struct{})
+	for _, s := range series {
+		if _, ok := userGroupsMap[s.Event.GroupID]; !ok {
+			continue
+		}
+		if _, ok := found[s.Event.ID]; ok {
+			continue
+		}
+		found[s.Event.ID] = struct{}{}
+		resp.Events = append(resp.Events, mapToSeriesResp(s))
+	}
+
+	for _, id := range changedEvents.ids {
+		strID := strconv.FormatInt(id, 10)
+		if _, ok := found[strID]; !ok {
+			resp.DeletedEvents = append(resp.DeletedEvents, strID)
+		}
+	}
+
+	var groupIDs []int64
+	for _, id := range changedGroups.ids {
+		if _, ok := userGroupsMap[id]; ok {
+			groupIDs = append(groupIDs, id)
+		} else {
+			resp.DeletedGroups = append(resp.DeletedGroups, id)
+		}
+	}
+
+	if len(groupIDs) != 0 {
+		groups, err := a.groups.GetGroups(r.Context(), a.db, groupIDs)
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get groups: %w", err))
+			return
+		}
+
+		settings, err := a.groups.GetUserGroupSettings(r.Context(), a.db, model.UserGroupSettingsFilter{
+			UserIDs:  []int64{userID},
+			GroupIDs: groupIDs,
+		})
+		if err != nil {
+			a.serverErrorResponse(w, r, fmt.Errorf("get groups settings: %w", err))
+			return
+		}
+
+		settingsMap := make(map[int64]*model.GroupSettings)
+		for _, s := range settings {
+			settingsMap[s.GroupID] = s
+		}
+
+		for _, g := range groups {
+			s, ok := settingsMap[g.ID]
+			if !ok {
+				// user left the group after the sync point was taken
+				continue
+			}
+
+			resp.Groups = append(resp.Groups, &syncGroupResp{
+				ID:        g.ID,
+				Name:      g.Name,
+				CreatorID: g.CreatorID,
+				Color:     "#" + s.Color.ToHTML(),
+				Notify:    s.Notify,
+				UsersIDs:  g.UsersIDs,
+				Roles:     g.Roles,
+				Version:   g.Version,
+			})
+		}
+	}
+
+	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func mapToSeriesResp(s *model.Series) *seriesResp {
+	e := s.Event
+
+	exceptions := make([]dateTime, 0, len(e.Exceptions))
+	for ts := range e.Exceptions {
+		exceptions = append(exceptions, dateTime(time.Unix(ts, 0)))
+	}
+	sort.Slice(exceptions, func(i, j int) bool {
+		return time.Time(exceptions[i]).Before(time.Time(exceptions[j]))
+	})
+
+	overrides := make([]*overrideResp, len(s.Overrides))
+	for i, o := range s.Overrides {
+		overrides[i] = &overrideResp{
+			RecurrenceID:  dateTime(o.OriginalStart),
+			EventType:     o.EventType,
+			Title:         o.Title,
+			Description:   o.Description,
+			AllDay:        o.AllDay,
+			From:          dateTime(o.From),
+			To:            dateTime(o.To),
+			Notifications: mapToNotificationTypesResp(o.Notifications),
+			NotifyOffsets: mapToDurationsResp(o.Notifications),
+			Attachments:   mapToAttachmentsResp(o.Attachments),
+			UpdatedBy:     o.UpdatedBy,
+			UpdatedAt:     dateTime(o.UpdatedAt),
+		}
+	}
+
+	return &seriesResp{
+		ID:            e.ID,
+		GroupID:       e.GroupID,
+		EventType:     e.EventType,
+		Title:         e.Title,
+		Description:   e.Description,
+		AllDay:        e.AllDay,
+		From:          dateTime(e.From),
+		To:            dateTime(e.To),
+		TimeZone:      e.TimeZone,
+		RepeatType:    e.RepeatType,
+		Recurrence:    mapToRecurrenceResp(e.Recurrence),
+		Exceptions:    exceptions,
+		Overrides:     overrides,
+		Notifications: mapToNotificationTypesResp(e.Notifications),
+		NotifyOffsets: mapToDurationsResp(e.Notifications),
+		NotifyAt:      mapToDateTimesResp(e.NotifyAt),
+		Attachments:   mapToAttachmentsResp(e.Attachments),
+		CreatorID:     e.CreatorID,
+		UpdatedBy:     e.UpdatedBy,
+		CreatedAt:     dateTime(e.CreatedAt),
+		UpdatedAt:     dateTime(e.UpdatedAt),
+		Version:       e.Version,
+	}
+}
+
+func mapToAttachmentsResp(attachments []*model.Attachment) []*attachment {
+	res, _ := mapSlice(attachments, func(a *model.Attachment) (*attachment, error) {
+		return &attachment{
+			Name: a.Name,
+			Path: a.Path,
+		}, nil
+	})
+
+	return res
+}
+
+func encodeSyncToken(syncPoint int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(syncPoint, 10)))
+}
+
+// decodeSyncToken returns zero sync point for the empty token, that means full sync.
+func decodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errInvalidSyncToken
+	}
+
+	syncPoint, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || syncPoint <= 0 {
+		return 0, errInvalidSyncToken
+	}
+
+	return syncPoint, nil
+}
+
+// idSet keeps ids in the order they were added, skipping duplicates.
+type idSet struct {
+	ids  []int64
+	seen map[int64]struct{}
+}
+
+func newIDSet() *idSet {
+	return &idSet{seen: make(map[int64]struct{})}
+}
+
+func (s *idSet) add(id int64) {
+	if _, ok := s.seen[id]; ok {
+		return
+	}
+	s.seen[id] = struct{}{}
+	s.ids = append(s.ids, id)
+}
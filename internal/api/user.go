@@ -25,6 +25,7 @@ func (a *Api) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		Email:       user.Email,
 		PhoneNumber: user.PhoneNumber,
 		Photo:       user.Photo,
+		TimeZone:    user.TimeZone,
 	}
 
 	if err := a.writeJSON(w, http.StatusOK, resp, nil); err != nil {
@@ -107,6 +108,7 @@ func parseQuery(r *http.Request) (*model.UserSearchFilter, error) {
 	return filter, nil
 }
 
+// updateUserPushTokenHandler registers the token of legacy clients, which don't send their session.
 func (a *Api) updateUserPushTokenHandler(w http.ResponseWriter, r *http.Request) {
 	user, ok := r.Context().Value(contextKeyUser).(*model.User)
 	if !ok {
@@ -131,8 +133,8 @@ func (a *Api) updateUserPushTokenHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := a.users.UpdateUserPushToken(r.Context(), a.db, user.ID, req.PushToken); err != nil {
-		a.serverErrorResponse(w, r, fmt.Errorf("update push token: %w", err))
+	if err := a.devices.UpsertLegacyDevice(r.Context(), a.db, user.ID, req.PushToken); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("upsert legacy device: %w", err))
 		return
 	}
 
@@ -162,3 +164,36 @@ func (a *Api) updateUserNotifyHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func (a *Api) updateUserTimeZoneHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextKeyUser).(*model.User)
+	if !ok {
+		a.serverErrorResponse(w, r, errCantRetrieveUser)
+		return
+	}
+
+	req := &struct {
+		TimeZone string `json:"time_zone"`
+	}{}
+
+	if err := a.readJSON(w, r, req); err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(req.TimeZone != "", "time_zone", "time zone must be provided")
+	v.Check(validTimeZone(req.TimeZone), "time_zone", "time zone must be valid IANA time zone")
+
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := a.users.UpdateTimeZone(r.Context(), a.db, user.ID, req.TimeZone); err != nil {
+		a.serverErrorResponse(w, r, fmt.Errorf("update time zone: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
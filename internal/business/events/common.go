@@ -8,40 +8,98 @@ import (
 	"github.com/teambition/rrule-go"
 )
 
-func getRule(t model.RepeatType, from time.Time, to *time.Time) (string, error) {
-	var freq rrule.Frequency
-	var interval int
+var frequencies = map[model.Frequency]rrule.Frequency{
+	model.FrequencyDaily:   rrule.DAILY,
+	model.FrequencyWeekly:  rrule.WEEKLY,
+	model.FrequencyMonthly: rrule.MONTHLY,
+	model.FrequencyYearly:  rrule.YEARLY,
+}
+
+var weekdays = map[time.Weekday]rrule.Weekday{
+	time.Monday:    rrule.MO,
+	time.Tuesday:   rrule.TU,
+	time.Wednesday: rrule.WE,
+	time.Thursday:  rrule.TH,
+	time.Friday:    rrule.FR,
+	time.Saturday:  rrule.SA,
+	time.Sunday:    rrule.SU,
+}
 
+func recurrenceFromRepeatType(t model.RepeatType) (*model.Recurrence, error) {
 	switch t {
 	case model.RepeatTypeNone:
-		return "", nil
+		return nil, nil
 	case model.RepeatTypeEveryDay:
-		freq = rrule.DAILY
-		interval = 1
+		return &model.Recurrence{Frequency: model.FrequencyDaily, Interval: 1}, nil
 	case model.RepeatTypeEveryThreeDays:
-		freq = rrule.DAILY
-		interval = 3
+		return &model.Recurrence{Frequency: model.FrequencyDaily, Interval: 3}, nil
 	case model.RepeatTypeEveryWeek:
-		freq = rrule.WEEKLY
-		interval = 1
+		return &model.Recurrence{Frequency: model.FrequencyWeekly, Interval: 1}, nil
 	case model.RepeatTypeEveryMonth:
-		freq = rrule.MONTHLY
-		interval = 1
+		return &model.Recurrence{Frequency: model.FrequencyMonthly, Interval: 1}, nil
 	case model.RepeatTypeEveryYear:
-		freq = rrule.YEARLY
-		interval = 1
+		return &model.Recurrence{Frequency: model.FrequencyYearly, Interval: 1}, nil
 	default:
-		return "", fmt.Errorf("unknown repeat type: %v", t)
+		return nil, fmt.Errorf("unknown repeat type: %v", t)
+	}
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("load location %q: %w", name, err)
+	}
+
+	return loc, nil
+}
+
+// shiftWallClock moves t the same way as before moved to after,
+// keeping wall-clock time of occurrences in the corresponding time zones.
+func shiftWallClock(t time.Time, oldLoc *time.Location, before, after time.Time, newLoc *time.Location) time.Time {
+	before = before.In(oldLoc)
+	after = after.In(newLoc)
+
+	beforeDate := time.Date(before.Year(), before.Month(), before.Day(), 0, 0, 0, 0, time.UTC)
+	afterDate := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(afterDate.Sub(beforeDate).Hours() / 24)
+
+	y, m, d := t.In(oldLoc).Date()
+	return time.Date(y, m, d+days, after.Hour(), after.Minute(), after.Second(), 0, newLoc)
+}
+
+func getRule(r *model.Recurrence, from time.Time, loc *time.Location) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	freq, ok := frequencies[r.Frequency]
+	if !ok {
+		return "", fmt.Errorf("unknown frequency: %v", r.Frequency)
 	}
 
 	opt := rrule.ROption{
-		Freq:     freq,
-		Interval: interval,
-		Dtstart:  from.UTC(),
+		Freq:       freq,
+		Interval:   r.Interval,
+		Dtstart:    from.In(loc),
+		Count:      r.Count,
+		Bymonthday: r.ByMonthDay,
+		Bysetpos:   r.BySetPos,
 	}
 
-	if to != nil {
-		opt.Until = *to
+	for _, d := range r.ByDay {
+		wd, ok := weekdays[d.Weekday]
+		if !ok {
+			return "", fmt.Errorf("unknown weekday: %v", d.Weekday)
+		}
+		opt.Byweekday = append(opt.Byweekday, wd.Nth(d.N))
+	}
+
+	if r.Until != nil {
+		opt.Until = r.Until.UTC()
 	}
 
 	rule, err := rrule.NewRRule(opt)
@@ -51,3 +109,227 @@ func getRule(t model.RepeatType, from time.Time, to *time.Time) (string, error)
 
 	return rule.String(), nil
 }
+
+// parseRule parses the stored rule and expands it in loc,
+// so that occurrences keep their wall-clock time across DST changes.
+func parseRule(rule string, loc *time.Location) (*rrule.RRule, error) {
+	rOption, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, fmt.Errorf("parse repeat rule %q: %w", rule, err)
+	}
+	rOption.Dtstart = rOption.Dtstart.In(loc)
+
+	res, err := rrule.NewRRule(*rOption)
+	if err != nil {
+		return nil, fmt.Errorf("make rule: %w", err)
+	}
+
+	return res, nil
+}
+
+func parseRecurrence(rule string) (*model.Recurrence, error) {
+	if rule == "" {
+		return nil, nil
+	}
+
+	rOption, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, fmt.Errorf("parse repeat rule %q: %w", rule, err)
+	}
+
+	res := &model.Recurrence{
+		Interval:   rOption.Interval,
+		ByMonthDay: rOption.Bymonthday,
+		BySetPos:   rOption.Bysetpos,
+		Count:      rOption.Count,
+	}
+
+	found := false
+	for f, rf := range frequencies {
+		if rf == rOption.Freq {
+			res.Frequency = f
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unsupported frequency: %v", rOption.Freq)
+	}
+
+	if res.Interval == 0 {
+		res.Interval = 1
+	}
+
+	for _, d := range rOption.Byweekday {
+		res.ByDay = append(res.ByDay, model.WeekdayNum{
+			Weekday: time.Weekday((d.Day() + 1) % 7),
+			N:       d.N(),
+		})
+	}
+
+	if !rOption.Until.IsZero() {
+		until := rOption.Until
+		res.Until = &until
+	}
+
+	return res, nil
+}
+
+// splitRecurrence divides the series at ts, so that the left part ends right before ts
+// and the right part starts from it. Left part is nil if there are no occurrences before ts.
+func splitRecurrence(r *model.Recurrence, from, ts time.Time, loc *time.Location) (*model.Recurrence, *model.Recurrence, error) {
+	left := *r
+	right := *r
+
+	if r.Count == 0 {
+		until := ts.Add(-time.Second)
+		left.Until = &until
+		return &left, &right, nil
+	}
+
+	rule, err := getRule(r, from, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed, err := parseRule(rule, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := len(parsed.Between(from, ts.Add(-time.Second), true))
+	if before == 0 {
+		return nil, &right, nil
+	}
+
+	left.Count = before
+	right.Count = r.Count - before
+
+	return &left, &right, nil
+}
+
+func splitExceptions(exceptions map[int64]struct{}, ts time.Time) (map[int64]struct{}, map[int64]struct{}) {
+	left := make(map[int64]struct{})
+	right := make(map[int64]struct{})
+
+	for e := range exceptions {
+		if e < ts.Unix() {
+			left[e] = struct{}{}
+		} else {
+			right[e] = struct{}{}
+		}
+	}
+
+	return left, right
+}
+
+func splitTimes(times []time.Time, ts time.Time) ([]time.Time, []time.Time) {
+	var left, right []time.Time
+	for _, t := range times {
+		if t.Before(ts) {
+			left = append(left, t)
+		} else {
+			right = append(right, t)
+		}
+	}
+
+	return left, right
+}
+
+func applyRepeatEnd(r *model.Recurrence, end *model.RepeatEnd) *model.Recurrence {
+	if r == nil || end == nil {
+		return r
+	}
+
+	res := *r
+	res.Count = end.Count
+	res.Until = end.Until
+
+	return &res
+}
+
+// getEndDate calculates the end of the last occurrence, nil means that the event repeats forever.
+func getEndDate(rule string, from, to time.Time, loc *time.Location) (*time.Time, error) {
+	if rule == "" {
+		return &to, nil
+	}
+
+	parsed, err := parseRule(rule, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var last time.Time
+	switch {
+	case parsed.OrigOptions.Count != 0:
+		all := parsed.All()
+		if len(all) != 0 {
+			last = all[len(all)-1]
+		}
+	case !parsed.OrigOptions.Until.IsZero():
+		last = parsed.Before(parsed.OrigOptions.Until, true)
+	default:
+		return nil, nil
+	}
+
+	if last.IsZero() {
+		last = from
+	}
+
+	end := last.Add(to.Sub(from))
+	return &end, nil
+}
+
+func shiftExceptions(exceptions map[int64]struct{}, oldLoc *time.Location, before, after time.Time, newLoc *time.Location) map[int64]struct{} {
+	res := make(map[int64]struct{}, len(exceptions))
+	for e := range exceptions {
+		res[shiftWallClock(time.Unix(e, 0), oldLoc, before, after, newLoc).Unix()] = struct{}{}
+	}
+
+	return res
+}
+
+// rebaseOverride attaches override to the changed series. Fields that were not changed
+// in the override follow the series, while changed ones are kept.
+func rebaseOverride(o *model.EventOverride, eventID int64, originalStart time.Time, oldEvent *model.Event, info *model.EventUpdate) *model.EventOverride {
+	res := *o
+	res.EventID = eventID
+	res.OriginalStart = originalStart
+
+	if o.From.Equal(o.OriginalStart) && o.To.Sub(o.From) == oldEvent.To.Sub(oldEvent.From) {
+		res.From = originalStart
+		res.To = originalStart.Add(info.To.Sub(info.From))
+	}
+
+	if o.EventType == oldEvent.EventType {
+		res.EventType = info.EventType
+	}
+	if o.Title == oldEvent.Title {
+		res.Title = info.Title
+	}
+	if o.Description == oldEvent.Description {
+		res.Description = info.Description
+	}
+	if o.AllDay == oldEvent.AllDay {
+		res.AllDay = info.AllDay
+	}
+	if equalDurations(o.Notifications, oldEvent.Notifications) {
+		res.Notifications = info.Notifications
+	}
+
+	return &res
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -8,33 +8,86 @@ import (
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 )
 
-func (s *Service) CreateEvent(ctx context.Context, info *model.EventCreate) (*model.Event, error) {
-	repeatRule := ""
-	if info.RepeatType != model.RepeatTypeNone {
+func (s *Service) CreateEvent(ctx context.Context, userID int64, info *model.EventCreate) (*model.Event, error) {
+	event, err := newEvent(userID, info)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := s.eventsRepository.CreateEvent(ctx, tx, event)
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.CreateEvent: %w", err)
+	}
+
+	event.ID = instanceID(id, info.From)
+
+	if err := s.notifyGroup(ctx, tx, &model.GroupChange{
+		GroupID: info.GroupID,
+		ActorID: userID,
+		Type:    model.ChangeTypeEventCreated,
+		EventID: event.ID,
+		Title:   info.Title,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+
+	return event, nil
+}
+
+// newEvent builds the stored representation of the event, info is normalized in place.
+func newEvent(userID int64, info *model.EventCreate) (*model.Event, error) {
+	if info.Recurrence == nil {
 		var err error
-		repeatRule, err = getRule(info.RepeatType, info.From, nil)
+		info.Recurrence, err = recurrenceFromRepeatType(info.RepeatType)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	var endDate *time.Time
-	if info.RepeatType == model.RepeatTypeNone {
-		endDate = &info.To
+	info.Recurrence = applyRepeatEnd(info.Recurrence, info.RepeatEnd)
+
+	if info.Recurrence == nil {
+		info.RepeatType = model.RepeatTypeNone
+	} else if info.RepeatType == model.RepeatTypeNone {
+		info.RepeatType = model.RepeatTypeCustom
 	}
 
-	event := &model.Event{
-		RepeatRule:  repeatRule,
-		Exceptions:  map[int64]struct{}{},
-		Until:       endDate,
-		EventCreate: *info,
+	loc, err := loadLocation(info.TimeZone)
+	if err != nil {
+		return nil, err
 	}
+	info.TimeZone = loc.String()
 
-	id, err := s.eventsRepository.CreateEvent(ctx, s.db, event)
+	repeatRule, err := getRule(info.Recurrence, info.From, loc)
 	if err != nil {
-		return nil, fmt.Errorf("eventsRepository.CreateEvent: %w", err)
+		return nil, err
 	}
 
-	event.ID = fmt.Sprintf("%v_%v", id, info.From.Unix())
-	return event, nil
+	endDate, err := getEndDate(repeatRule, info.From, info.To, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Event{
+		RepeatRule:  repeatRule,
+		Exceptions:  map[int64]struct{}{},
+		Until:       endDate,
+		CreatorID:   userID,
+		UpdatedBy:   userID,
+		EventCreate: *info,
+	}, nil
 }
@@ -3,35 +3,194 @@ package events
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 )
 
-func (s *Service) DeleteEvent(ctx context.Context, id int64) error {
-	if err := s.eventsRepository.DeleteEvent(ctx, s.db, id); err != nil {
+// DeleteEvent deletes the event, overrides of the series are deleted by cascade.
+func (s *Service) DeleteEvent(ctx context.Context, userID int64, id int64, version int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	if err := s.eventsRepository.DeleteEvent(ctx, tx, id); err != nil {
 		return fmt.Errorf("eventsRepository.DeleteEvent: %w", err)
 	}
 
+	if err := s.notifyGroup(ctx, tx, &model.GroupChange{
+		GroupID: oldEvent.GroupID,
+		ActorID: userID,
+		Type:    model.ChangeTypeEventCancelled,
+		EventID: instanceID(id, oldEvent.From),
+		Title:   oldEvent.Title,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
 	return nil
 }
 
-func (s *Service) DeleteEventInstance(ctx context.Context, id int64, ts time.Time) error {
-	oldEvent, err := s.eventsRepository.GetEventByID(ctx, s.db, id)
+func (s *Service) DeleteEventInstance(ctx context.Context, userID int64, id int64, version int64, ts time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("get old event: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
+	if err != nil {
+		return err
 	}
 
 	oldEvent.Exceptions[ts.Unix()] = struct{}{}
-	if err := s.eventsRepository.UpdateEvent(ctx, s.db, &model.Event{
+	if err := s.eventsRepository.UpdateEvent(ctx, tx, &model.Event{
 		ID:          oldEvent.ID,
 		RepeatRule:  oldEvent.RepeatRule,
 		Exceptions:  oldEvent.Exceptions,
 		Until:       oldEvent.Until,
+		UpdatedBy:   userID,
 		EventCreate: oldEvent.EventCreate,
 	}); err != nil {
 		return fmt.Errorf("eventsRepository.UpdateEvent: %w", err)
 	}
 
+	if err := s.eventsRepository.DeleteOverride(ctx, tx, id, ts); err != nil {
+		return fmt.Errorf("eventsRepository.DeleteOverride: %w", err)
+	}
+
+	if err := s.notifyGroup(ctx, tx, &model.GroupChange{
+		GroupID: oldEvent.GroupID,
+		ActorID: userID,
+		Type:    model.ChangeTypeInstanceDeleted,
+		EventID: instanceID(id, ts),
+		Title:   oldEvent.Title,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) DeleteEventFollowing(ctx context.Context, userID int64, id int64, version int64, ts time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deleteEventFollowing(ctx, tx, userID, oldEvent, ts); err != nil {
+		return err
+	}
+
+	if err := s.notifyGroup(ctx, tx, &model.GroupChange{
+		GroupID: oldEvent.GroupID,
+		ActorID: userID,
+		Type:    model.ChangeTypeEventCancelled,
+		EventID: instanceID(id, ts),
+		Title:   oldEvent.Title,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) deleteEventFollowing(ctx context.Context, q database.Queryable, userID int64, oldEvent *model.Event, ts time.Time) error {
+	id, err := strconv.ParseInt(oldEvent.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse event id %q: %w", oldEvent.ID, err)
+	}
+
+	if oldEvent.RepeatType == model.RepeatTypeNone || !ts.After(oldEvent.From) {
+		if err := s.eventsRepository.DeleteEvent(ctx, q, id); err != nil {
+			return fmt.Errorf("eventsRepository.DeleteEvent: %w", err)
+		}
+		return nil
+	}
+
+	recurrence, err := parseRecurrence(oldEvent.RepeatRule)
+	if err != nil {
+		return err
+	}
+
+	loc, err := loadLocation(oldEvent.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	leftRecurrence, _, err := splitRecurrence(recurrence, oldEvent.From, ts, loc)
+	if err != nil {
+		return err
+	}
+
+	if leftRecurrence == nil {
+		if err := s.eventsRepository.DeleteEvent(ctx, q, id); err != nil {
+			return fmt.Errorf("eventsRepository.DeleteEvent: %w", err)
+		}
+		return nil
+	}
+
+	leftRule, err := getRule(leftRecurrence, oldEvent.From, loc)
+	if err != nil {
+		return err
+	}
+
+	leftEndDate, err := getEndDate(leftRule, oldEvent.From, oldEvent.To, loc)
+	if err != nil {
+		return err
+	}
+
+	leftExceptions, _ := splitExceptions(oldEvent.Exceptions, ts)
+
+	if err := s.eventsRepository.UpdateEvent(ctx, q, &model.Event{
+		ID:          oldEvent.ID,
+		RepeatRule:  leftRule,
+		Exceptions:  leftExceptions,
+		Until:       leftEndDate,
+		UpdatedBy:   userID,
+		EventCreate: oldEvent.EventCreate,
+	}); err != nil {
+		return fmt.Errorf("eventsRepository.UpdateEvent: %w", err)
+	}
+
+	if err := s.eventsRepository.DeleteOverrides(ctx, q, id, ts); err != nil {
+		return fmt.Errorf("eventsRepository.DeleteOverrides: %w", err)
+	}
+
 	return nil
 }
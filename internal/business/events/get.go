@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
-	"github.com/teambition/rrule-go"
 )
 
 func (s *Service) GetEventByID(ctx context.Context, id int64, ts time.Time) (*model.Event, error) {
@@ -20,19 +20,22 @@ func (s *Service) GetEventByID(ctx context.Context, id int64, ts time.Time) (*mo
 		if !event.From.Equal(ts) {
 			return nil, model.ErrNoRecord
 		}
-		return &model.Event{
-			ID:          fmt.Sprintf("%v_%v", event.ID, event.From.Unix()),
-			EventCreate: event.EventCreate,
-		}, err
+		return newSingleInstance(event), nil
 	}
 
-	rOption, err := rrule.StrToROption(event.RepeatRule)
+	loc, err := loadLocation(event.TimeZone)
 	if err != nil {
-		return nil, fmt.Errorf("parse repeat rule %q: %w", event.RepeatRule, err)
+		return nil, err
 	}
-	rule, err := rrule.NewRRule(*rOption)
+
+	rule, err := parseRule(event.RepeatRule, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	recurrence, err := parseRecurrence(event.RepeatRule)
 	if err != nil {
-		return nil, fmt.Errorf("make rule: %w", err)
+		return nil, err
 	}
 
 	if !rule.After(ts, true).Equal(ts) {
@@ -43,24 +46,22 @@ func (s *Service) GetEventByID(ctx context.Context, id int64, ts time.Time) (*mo
 		return nil, model.ErrNoRecord
 	}
 
-	duration := event.To.Sub(event.From)
-	return &model.Event{
-		ID:         fmt.Sprintf("%v_%v", event.ID, ts.Unix()),
-		RepeatRule: event.RepeatRule,
-		Exceptions: event.Exceptions,
-		EventCreate: model.EventCreate{
-			GroupID:       event.GroupID,
-			EventType:     event.EventType,
-			Title:         event.Title,
-			Description:   event.Description,
-			AllDay:        event.AllDay,
-			From:          ts,
-			To:            ts.Add(duration),
-			RepeatType:    event.RepeatType,
-			Notifications: event.Notifications,
-			Attachments:   event.Attachments,
-		},
-	}, nil
+	overrides, err := s.eventsRepository.GetOverrides(ctx, s.db, model.OverridesFilter{
+		EventIDs: []int64{id},
+		From:     ts,
+		To:       ts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.GetOverrides: %w", err)
+	}
+
+	for _, o := range overrides {
+		if o.OriginalStart.Equal(ts) {
+			return newOverriddenInstance(event, o, recurrence), nil
+		}
+	}
+
+	return newInstance(event, ts, recurrence), nil
 }
 
 func (s *Service) GetEvents(ctx context.Context, filter model.EventsFilter) ([]*model.Event, error) {
@@ -69,28 +70,38 @@ func (s *Service) GetEvents(ctx context.Context, filter model.EventsFilter) ([]*
 		return nil, fmt.Errorf("eventsRepository.GetEvents: %w", err)
 	}
 
+	overrides, err := s.getOverrides(ctx, baseEvents, filter)
+	if err != nil {
+		return nil, err
+	}
+
 	var res []*model.Event
 
 	for _, e := range baseEvents {
 		if e.RepeatType == model.RepeatTypeNone {
-			res = append(res, &model.Event{
-				ID:          fmt.Sprintf("%v_%v", e.ID, e.From.Unix()),
-				EventCreate: e.EventCreate,
-			})
+			res = append(res, newSingleInstance(e))
 			continue
 		}
 
 		duration := e.To.Sub(e.From)
 
-		rOption, err := rrule.StrToROption(e.RepeatRule)
+		loc, err := loadLocation(e.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := parseRule(e.RepeatRule, loc)
 		if err != nil {
-			return nil, fmt.Errorf("parse repeat rule %q: %w", e.RepeatRule, err)
+			return nil, err
 		}
-		rule, err := rrule.NewRRule(*rOption)
+
+		recurrence, err := parseRecurrence(e.RepeatRule)
 		if err != nil {
-			return nil, fmt.Errorf("make rule: %w", err)
+			return nil, err
 		}
 
+		eventOverrides := overrides[e.ID]
+
 		repeats := rule.Between(e.From, filter.To.Add(-1), true)
 		for _, r := range repeats {
 			from := r
@@ -104,23 +115,23 @@ func (s *Service) GetEvents(ctx context.Context, filter model.EventsFilter) ([]*
 				continue
 			}
 
-			res = append(res, &model.Event{
-				ID:         fmt.Sprintf("%v_%v", e.ID, from.Unix()),
-				RepeatRule: e.RepeatRule,
-				Exceptions: e.Exceptions,
-				EventCreate: model.EventCreate{
-					GroupID:       e.GroupID,
-					EventType:     e.EventType,
-					Title:         e.Title,
-					Description:   e.Description,
-					AllDay:        e.AllDay,
-					From:          from,
-					To:            to,
-					RepeatType:    e.RepeatType,
-					Notifications: e.Notifications,
-					Attachments:   e.Attachments,
-				},
-			})
+			if _, ok := eventOverrides[r.Unix()]; ok {
+				continue
+			}
+
+			res = append(res, newInstance(e, from, recurrence))
+		}
+
+		for _, o := range eventOverrides {
+			if filter.To.Before(o.From) || o.To.Before(filter.From) {
+				continue
+			}
+
+			if _, ok := e.Exceptions[o.OriginalStart.Unix()]; ok {
+				continue
+			}
+
+			res = append(res, newOverriddenInstance(e, o, recurrence))
 		}
 	}
 
@@ -130,3 +141,159 @@ func (s *Service) GetEvents(ctx context.Context, filter model.EventsFilter) ([]*
 
 	return res, nil
 }
+
+// GetSeries returns stored events with their overrides without expanding occurrences,
+// so that clients can expand them by themselves.
+func (s *Service) GetSeries(ctx context.Context, filter model.EventsFilter) ([]*model.Series, error) {
+	events, err := s.eventsRepository.GetEvents(ctx, s.db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.GetEvents: %w", err)
+	}
+
+	res := make([]*model.Series, len(events))
+	if len(events) == 0 {
+		return res, nil
+	}
+
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i], err = strconv.ParseInt(e.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse event id %q: %w", e.ID, err)
+		}
+	}
+
+	overrides, err := s.eventsRepository.GetOverrides(ctx, s.db, model.OverridesFilter{EventIDs: ids})
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.GetOverrides: %w", err)
+	}
+
+	overridesMap := make(map[int64][]*model.EventOverride)
+	for _, o := range overrides {
+		overridesMap[o.EventID] = append(overridesMap[o.EventID], o)
+	}
+
+	for i, e := range events {
+		e.Recurrence, err = parseRecurrence(e.RepeatRule)
+		if err != nil {
+			return nil, err
+		}
+
+		res[i] = &model.Series{
+			Event:     e,
+			Overrides: overridesMap[ids[i]],
+		}
+	}
+
+	return res, nil
+}
+
+// getOverrides returns overrides for the repeating events mapped by series id and original start.
+func (s *Service) getOverrides(ctx context.Context, events []*model.Event, filter model.EventsFilter) (map[string]map[int64]*model.EventOverride, error) {
+	var ids []int64
+	for _, e := range events {
+		if e.RepeatType == model.RepeatTypeNone {
+			continue
+		}
+
+		id, err := strconv.ParseInt(e.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse event id %q: %w", e.ID, err)
+		}
+		ids = append(ids, id)
+	}
+
+	res := make(map[string]map[int64]*model.EventOverride)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	overrides, err := s.eventsRepository.GetOverrides(ctx, s.db, model.OverridesFilter{
+		EventIDs: ids,
+		From:     filter.From,
+		To:       filter.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.GetOverrides: %w", err)
+	}
+
+	for _, o := range overrides {
+		id := strconv.FormatInt(o.EventID, 10)
+		if _, ok := res[id]; !ok {
+			res[id] = make(map[int64]*model.EventOverride)
+		}
+		res[id][o.OriginalStart.Unix()] = o
+	}
+
+	return res, nil
+}
+
+func newSingleInstance(e *model.Event) *model.Event {
+	return &model.Event{
+		ID:          fmt.Sprintf("%v_%v", e.ID, e.From.Unix()),
+		CreatorID:   e.CreatorID,
+		UpdatedBy:   e.UpdatedBy,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+		Version:     e.Version,
+		EventCreate: e.EventCreate,
+	}
+}
+
+func newInstance(e *model.Event, from time.Time, recurrence *model.Recurrence) *model.Event {
+	return &model.Event{
+		ID:         fmt.Sprintf("%v_%v", e.ID, from.Unix()),
+		RepeatRule: e.RepeatRule,
+		Exceptions: e.Exceptions,
+		CreatorID:  e.CreatorID,
+		UpdatedBy:  e.UpdatedBy,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+		Version:    e.Version,
+		EventCreate: model.EventCreate{
+			GroupID:       e.GroupID,
+			EventType:     e.EventType,
+			Title:         e.Title,
+			Description:   e.Description,
+			AllDay:        e.AllDay,
+			From:          from,
+			To:            from.Add(e.To.Sub(e.From)),
+			TimeZone:      e.TimeZone,
+			RepeatType:    e.RepeatType,
+			Recurrence:    recurrence,
+			Notifications: e.Notifications,
+			NotifyAt:      e.NotifyAt,
+			Attachments:   e.Attachments,
+		},
+	}
+}
+
+func newOverriddenInstance(e *model.Event, o *model.EventOverride, recurrence *model.Recurrence) *model.Event {
+	recurrenceID := o.OriginalStart
+	return &model.Event{
+		ID:           fmt.Sprintf("%v_%v", e.ID, o.OriginalStart.Unix()),
+		RepeatRule:   e.RepeatRule,
+		Exceptions:   e.Exceptions,
+		RecurrenceID: &recurrenceID,
+		CreatorID:    e.CreatorID,
+		UpdatedBy:    o.UpdatedBy,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+		Version:      e.Version,
+		EventCreate: model.EventCreate{
+			GroupID:       e.GroupID,
+			EventType:     o.EventType,
+			Title:         o.Title,
+			Description:   o.Description,
+			AllDay:        o.AllDay,
+			From:          o.From,
+			To:            o.To,
+			TimeZone:      e.TimeZone,
+			RepeatType:    e.RepeatType,
+			Recurrence:    recurrence,
+			Notifications: o.Notifications,
+			NotifyAt:      e.NotifyAt,
+			Attachments:   o.Attachments,
+		},
+	}
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+const reasonAlreadyImported = "event with the same UID was already imported to the group"
+
+var errInvalidEvent = errors.New("invalid event")
+
+// ImportEvents creates the imported events in the group. Events with UIDs that are already present
+// in the group are skipped, so that importing the same file again does not create duplicates.
+// Each event is created in its own transaction, the report lists imported and skipped events.
+func (s *Service) ImportEvents(ctx context.Context, userID int64, groupID int64, events []*model.ImportedEvent) ([]*model.ImportItem, error) {
+	res := make([]*model.ImportItem, 0, len(events))
+	if len(events) == 0 {
+		return res, nil
+	}
+
+	uids := make([]string, len(events))
+	for i, e := range events {
+		uids[i] = e.UID
+	}
+
+	existing, err := s.eventsRepository.GetEvents(ctx, s.db, model.EventsFilter{
+		GroupIDs: []int64{groupID},
+		UIDs:     uids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.GetEvents: %w", err)
+	}
+
+	imported := make(map[string]struct{}, len(existing))
+	for _, e := range existing {
+		imported[e.UID] = struct{}{}
+	}
+
+	for _, e := range events {
+		item := &model.ImportItem{
+			UID:    e.UID,
+			Title:  e.Title,
+			Status: model.ImportStatusImported,
+			Reason: strings.Join(e.Warnings, "; "),
+		}
+		res = append(res, item)
+
+		if _, ok := imported[e.UID]; ok {
+			item.Status = model.ImportStatusSkipped
+			item.Reason = reasonAlreadyImported
+			continue
+		}
+
+		if err := s.importEvent(ctx, userID, groupID, e); err != nil {
+			switch {
+			case errors.Is(err, model.ErrAlreadyExists):
+				// imported concurrently
+				item.Status = model.ImportStatusSkipped
+				item.Reason = reasonAlreadyImported
+			case errors.Is(err, errInvalidEvent):
+				item.Status = model.ImportStatusUnsupported
+				item.Reason = "event can't be represented in the planner"
+			default:
+				return nil, err
+			}
+			continue
+		}
+
+		imported[e.UID] = struct{}{}
+	}
+
+	return res, nil
+}
+
+func (s *Service) importEvent(ctx context.Context, userID int64, groupID int64, e *model.ImportedEvent) error {
+	info := e.EventCreate
+	info.GroupID = groupID
+
+	event, err := newEvent(userID, &info)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidEvent, err)
+	}
+	if e.Exceptions != nil {
+		event.Exceptions = e.Exceptions
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := s.eventsRepository.CreateEvent(ctx, tx, event)
+	if err != nil {
+		return fmt.Errorf("eventsRepository.CreateEvent: %w", err)
+	}
+
+	for _, o := range e.Overrides {
+		override := *o
+		override.EventID = id
+		override.UpdatedBy = userID
+
+		if err := s.eventsRepository.UpsertOverride(ctx, tx, &override); err != nil {
+			return fmt.Errorf("eventsRepository.UpsertOverride: %w", err)
+		}
+	}
+
+	if err := s.notifyGroup(ctx, tx, &model.GroupChange{
+		GroupID: groupID,
+		ActorID: userID,
+		Type:    model.ChangeTypeEventCreated,
+		EventID: instanceID(id, info.From),
+		Title:   info.Title,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// notifyGroup schedules the push about the change for other members of the group. It is sent after
+// the delay, so that following edits are merged into it.
+func (s *Service) notifyGroup(ctx context.Context, q database.Queryable, change *model.GroupChange) error {
+	if err := s.outboxRepository.AddChangeNotifications(ctx, q, change, time.Now().Add(config.ChangeNotifyDelay()), config.ChangeNotifyMaxDelay()); err != nil {
+		return fmt.Errorf("outboxRepository.AddChangeNotifications: %w", err)
+	}
+
+	return nil
+}
+
+// rescheduled reports whether the occurrence that started at ts got another time.
+func rescheduled(oldEvent *model.Event, ts time.Time, from, to time.Time) bool {
+	return !from.Equal(ts) || to.Sub(from) != oldEvent.To.Sub(oldEvent.From)
+}
+
+func instanceID(id int64, ts time.Time) string {
+	return fmt.Sprintf("%v_%v", id, ts.Unix())
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// RefreshSchedules moves the reminder schedule of the series past the given time. It is called
+// by the notifications sender once reminders before that time are stored in the outbox.
+func (s *Service) RefreshSchedules(ctx context.Context, ids []int64, after time.Time) error {
+	for _, id := range ids {
+		if err := s.refreshSchedule(ctx, s.db, id, after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refreshSchedule computes when every reminder offset of the series is sent next after the given time.
+// Offsets are taken from the series, its overrides and personal reminders of the members, the offset
+// is scheduled for every occurrence, even if it is used only by some of them.
+func (s *Service) refreshSchedule(ctx context.Context, q database.Queryable, id int64, after time.Time) error {
+	event, err := s.eventsRepository.GetEventByID(ctx, q, id)
+	if err != nil {
+		// schedule of the deleted series is deleted with it
+		if errors.Is(err, model.ErrNoRecord) {
+			return nil
+		}
+		return fmt.Errorf("eventsRepository.GetEventByID: %w", err)
+	}
+
+	overrides, err := s.eventsRepository.GetOverrides(ctx, q, model.OverridesFilter{EventIDs: []int64{id}})
+	if err != nil {
+		return fmt.Errorf("eventsRepository.GetOverrides: %w", err)
+	}
+
+	reminders, err := s.remindersRepository.GetReminders(ctx, q, model.RemindersFilter{EventIDs: []int64{id}})
+	if err != nil {
+		return fmt.Errorf("remindersRepository.GetReminders: %w", err)
+	}
+
+	offsets := make(map[time.Duration]struct{})
+	for _, n := range event.Notifications {
+		offsets[n] = struct{}{}
+	}
+	for _, o := range overrides {
+		for _, n := range o.Notifications {
+			offsets[n] = struct{}{}
+		}
+	}
+	for _, r := range reminders {
+		if r.Custom && !r.Muted {
+			for _, n := range r.Notifications {
+				offsets[n] = struct{}{}
+			}
+		}
+	}
+
+	var schedules []*model.ReminderSchedule
+	for offset := range offsets {
+		start, ok, err := nextStart(event, overrides, after.Add(offset))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		schedules = append(schedules, &model.ReminderSchedule{
+			EventID: id,
+			Offset:  offset,
+			NextAt:  start.Add(-offset),
+		})
+	}
+
+	if err := s.remindersRepository.SetSchedules(ctx, q, id, schedules); err != nil {
+		return fmt.Errorf("remindersRepository.SetSchedules: %w", err)
+	}
+
+	return nil
+}
+
+// nextStart returns the first start of an occurrence at or after t, overridden occurrences
+// start at their overridden time. False is returned if the series has ended.
+func nextStart(e *model.Event, overrides []*model.EventOverride, t time.Time) (time.Time, bool, error) {
+	if e.RepeatType == model.RepeatTypeNone {
+		return e.From, !e.From.Before(t), nil
+	}
+
+	var res time.Time
+	found := false
+
+	overridden := make(map[int64]struct{}, len(overrides))
+	for _, o := range overrides {
+		overridden[o.OriginalStart.Unix()] = struct{}{}
+
+		if _, ok := e.Exceptions[o.OriginalStart.Unix()]; ok {
+			continue
+		}
+
+		if !o.From.Before(t) && (!found || o.From.Before(res)) {
+			res, found = o.From, true
+		}
+	}
+
+	loc, err := loadLocation(e.TimeZone)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	rule, err := parseRule(e.RepeatRule, loc)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	for r := rule.After(t, true); !r.IsZero(); r = rule.After(r, false) {
+		if _, ok := e.Exceptions[r.Unix()]; ok {
+			continue
+		}
+		if _, ok := overridden[r.Unix()]; ok {
+			continue
+		}
+
+		if !found || r.Before(res) {
+			res, found = r, true
+		}
+		break
+	}
+
+	return res, found, nil
+}
@@ -2,27 +2,63 @@ package events
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 )
 
 type Service struct {
-	db               database.PGX
-	eventsRepository eventsRepository
+	db                  database.PGX
+	eventsRepository    eventsRepository
+	remindersRepository remindersRepository
+	outboxRepository    outboxRepository
 }
 
 type eventsRepository interface {
 	CreateEvent(ctx context.Context, q database.Queryable, event *model.Event) (int64, error)
 	GetEventByID(ctx context.Context, q database.Queryable, id int64) (*model.Event, error)
+	GetEventByIDForUpdate(ctx context.Context, q database.Queryable, id int64) (*model.Event, error)
 	GetEvents(ctx context.Context, q database.Queryable, filter model.EventsFilter) ([]*model.Event, error)
 	UpdateEvent(ctx context.Context, q database.Queryable, event *model.Event) error
 	DeleteEvent(ctx context.Context, q database.Queryable, id int64) error
+	GetOverrides(ctx context.Context, q database.Queryable, filter model.OverridesFilter) ([]*model.EventOverride, error)
+	UpsertOverride(ctx context.Context, q database.Queryable, override *model.EventOverride) error
+	DeleteOverride(ctx context.Context, q database.Queryable, eventID int64, originalStart time.Time) error
+	DeleteOverrides(ctx context.Context, q database.Queryable, eventID int64, from time.Time) error
 }
 
-func NewService(db database.PGX, repo eventsRepository) *Service {
+type remindersRepository interface {
+	GetReminders(ctx context.Context, q database.Queryable, filter model.RemindersFilter) ([]*model.Reminders, error)
+	SplitReminders(ctx context.Context, q database.Queryable, fromEventID int64, toEventID int64, ts time.Time) error
+	SetSchedules(ctx context.Context, q database.Queryable, eventID int64, schedules []*model.ReminderSchedule) error
+}
+
+type outboxRepository interface {
+	AddChangeNotifications(ctx context.Context, q database.Queryable, change *model.GroupChange, sendAt time.Time, maxDelay time.Duration) error
+}
+
+// lockEvent reads the event locking it until the end of the transaction and checks
+// that it was not changed since the version the client has seen. model.AnyVersion skips the check.
+func (s *Service) lockEvent(ctx context.Context, q database.Queryable, id int64, version int64) (*model.Event, error) {
+	event, err := s.eventsRepository.GetEventByIDForUpdate(ctx, q, id)
+	if err != nil {
+		return nil, fmt.Errorf("eventsRepository.GetEventByIDForUpdate: %w", err)
+	}
+
+	if version != model.AnyVersion && event.Version != version {
+		return nil, model.ErrEditConflict
+	}
+
+	return event, nil
+}
+
+func NewService(db database.PGX, repo eventsRepository, reminders remindersRepository, outbox outboxRepository) *Service {
 	return &Service{
-		db:               db,
-		eventsRepository: repo,
+		db:                  db,
+		eventsRepository:    repo,
+		remindersRepository: reminders,
+		outboxRepository:    outbox,
 	}
 }
@@ -3,50 +3,94 @@ package events
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 )
 
-func (s *Service) UpdateEvent(ctx context.Context, id int64, ts time.Time, info *model.EventUpdate) error {
-	oldEvent, err := s.eventsRepository.GetEventByID(ctx, s.db, id)
+func (s *Service) UpdateEvent(ctx context.Context, userID int64, id int64, version int64, ts time.Time, info *model.EventUpdate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("get old event: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	diff := info.From.Sub(ts)
-	from := oldEvent.From.Add(diff)
-	to := from.Add(info.To.Sub(info.From))
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
 
-	repeatRule := oldEvent.RepeatRule
-	if oldEvent.RepeatType != model.RepeatTypeNone && !oldEvent.From.Equal(from) {
-		var err error
-		repeatRule, err = getRule(oldEvent.RepeatType, from, nil)
+	if err := s.updateEvent(ctx, tx, userID, oldEvent, ts, info); err != nil {
+		return err
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) updateEvent(ctx context.Context, q database.Queryable, userID int64, oldEvent *model.Event, ts time.Time, info *model.EventUpdate) error {
+	id, err := strconv.ParseInt(oldEvent.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse event id %q: %w", oldEvent.ID, err)
+	}
+
+	oldLoc, err := loadLocation(oldEvent.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	newLoc := oldLoc
+	if info.TimeZone != "" {
+		newLoc, err = loadLocation(info.TimeZone)
 		if err != nil {
 			return err
 		}
 	}
 
+	from := info.From
 	exceptions := oldEvent.Exceptions
-	if diff != 0 {
-		newExceptions := make(map[int64]struct{}, len(oldEvent.Exceptions))
-		for e := range oldEvent.Exceptions {
-			newExceptions[time.Unix(e, 0).Add(diff).Unix()] = struct{}{}
+	repeatRule := oldEvent.RepeatRule
+	if oldEvent.RepeatType != model.RepeatTypeNone {
+		from = shiftWallClock(oldEvent.From, oldLoc, ts, info.From, newLoc)
+		exceptions = shiftExceptions(oldEvent.Exceptions, oldLoc, ts, info.From, newLoc)
+
+		recurrence, err := parseRecurrence(oldEvent.RepeatRule)
+		if err != nil {
+			return err
+		}
+
+		repeatRule, err = getRule(applyRepeatEnd(recurrence, info.RepeatEnd), from, newLoc)
+		if err != nil {
+			return err
 		}
+	}
+	to := from.Add(info.To.Sub(info.From))
 
-		exceptions = newExceptions
+	endDate, err := getEndDate(repeatRule, from, to, newLoc)
+	if err != nil {
+		return err
 	}
 
-	var endDate *time.Time
-	if oldEvent.RepeatType == model.RepeatTypeNone {
-		endDate = &to
+	overrides, err := s.eventsRepository.GetOverrides(ctx, q, model.OverridesFilter{EventIDs: []int64{id}})
+	if err != nil {
+		return fmt.Errorf("eventsRepository.GetOverrides: %w", err)
 	}
 
-	if err := s.eventsRepository.UpdateEvent(ctx, s.db, &model.Event{
+	if err := s.eventsRepository.UpdateEvent(ctx, q, &model.Event{
 		ID:         oldEvent.ID,
 		RepeatRule: repeatRule,
 		Exceptions: exceptions,
 		Until:      endDate,
+		UpdatedBy:  userID,
 		EventCreate: model.EventCreate{
 			GroupID:       info.GroupID,
 			EventType:     info.EventType,
@@ -55,44 +99,276 @@ func (s *Service) UpdateEvent(ctx context.Context, id int64, ts time.Time, info
 			AllDay:        info.AllDay,
 			From:          from,
 			To:            to,
+			TimeZone:      newLoc.String(),
 			RepeatType:    oldEvent.RepeatType,
 			Notifications: info.Notifications,
+			NotifyAt:      info.NotifyAt,
 			Attachments:   oldEvent.Attachments,
 		},
 	}); err != nil {
 		return fmt.Errorf("eventsRepository.UpdateEvent: %w", err)
 	}
 
-	return nil
+	if rescheduled(oldEvent, ts, info.From, info.To) {
+		if err := s.notifyGroup(ctx, q, &model.GroupChange{
+			GroupID: info.GroupID,
+			ActorID: userID,
+			Type:    model.ChangeTypeEventRescheduled,
+			EventID: instanceID(id, info.From),
+			Title:   info.Title,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return s.moveOverrides(ctx, q, userID, overrides, id, oldEvent, info, oldLoc, ts, newLoc)
 }
 
-func (s *Service) UpdateEventInstance(ctx context.Context, id int64, ts time.Time, info *model.EventUpdate) error {
-	oldEvent, err := s.eventsRepository.GetEventByID(ctx, s.db, id)
+func (s *Service) UpdateEventInstance(ctx context.Context, userID int64, id int64, version int64, ts time.Time, info *model.EventUpdate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("get old event: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
 	if err != nil {
-		return fmt.Errorf("begin tx")
+		return err
 	}
-	defer tx.Rollback(ctx)
 
-	oldEvent.Exceptions[ts.Unix()] = struct{}{}
+	var changes []*model.GroupChange
+	if info.GroupID == oldEvent.GroupID {
+		if err := s.eventsRepository.UpsertOverride(ctx, tx, &model.EventOverride{
+			EventID:       id,
+			OriginalStart: ts,
+			EventType:     info.EventType,
+			Title:         info.Title,
+			Description:   info.Description,
+			AllDay:        info.AllDay,
+			From:          info.From,
+			To:            info.To,
+			Notifications: info.Notifications,
+			Attachments:   oldEvent.Attachments,
+			UpdatedBy:     userID,
+		}); err != nil {
+			return fmt.Errorf("eventsRepository.UpsertOverride: %w", err)
+		}
+
+		// overridden instance keeps the id of the original occurrence
+		if rescheduled(oldEvent, ts, info.From, info.To) {
+			changes = append(changes, &model.GroupChange{
+				GroupID: info.GroupID,
+				ActorID: userID,
+				Type:    model.ChangeTypeEventRescheduled,
+				EventID: instanceID(id, ts),
+				Title:   info.Title,
+			})
+		}
+	} else {
+		// instance moved to another group can't stay a part of the series
+		oldEvent.Exceptions[ts.Unix()] = struct{}{}
+
+		if err := s.eventsRepository.DeleteOverride(ctx, tx, id, ts); err != nil {
+			return fmt.Errorf("eventsRepository.DeleteOverride: %w", err)
+		}
+
+		newID, err := s.eventsRepository.CreateEvent(ctx, tx, &model.Event{
+			RepeatRule: "",
+			Exceptions: map[int64]struct{}{},
+			Until:      &info.To,
+			CreatorID:  oldEvent.CreatorID,
+			UpdatedBy:  userID,
+			EventCreate: model.EventCreate{
+				GroupID:       info.GroupID,
+				EventType:     info.EventType,
+				Title:         info.Title,
+				Description:   info.Description,
+				AllDay:        info.AllDay,
+				From:          info.From,
+				To:            info.To,
+				TimeZone:      oldEvent.TimeZone,
+				RepeatType:    model.RepeatTypeNone,
+				Notifications: info.Notifications,
+				Attachments:   oldEvent.Attachments,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("eventsRepository.CreateEvent: %w", err)
+		}
+
+		if err := s.refreshSchedule(ctx, tx, newID, time.Now()); err != nil {
+			return err
+		}
+
+		// for members of the groups the instance is deleted from one group and created in another
+		changes = append(changes,
+			&model.GroupChange{
+				GroupID: oldEvent.GroupID,
+				ActorID: userID,
+				Type:    model.ChangeTypeInstanceDeleted,
+				EventID: instanceID(id, ts),
+				Title:   oldEvent.Title,
+			},
+			&model.GroupChange{
+				GroupID: info.GroupID,
+				ActorID: userID,
+				Type:    model.ChangeTypeEventCreated,
+				EventID: instanceID(newID, info.From),
+				Title:   info.Title,
+			},
+		)
+	}
+
+	// series is updated even if only the override changed, so that its version is bumped
 	if err := s.eventsRepository.UpdateEvent(ctx, tx, &model.Event{
 		ID:          oldEvent.ID,
 		RepeatRule:  oldEvent.RepeatRule,
 		Exceptions:  oldEvent.Exceptions,
 		Until:       oldEvent.Until,
+		UpdatedBy:   userID,
 		EventCreate: oldEvent.EventCreate,
 	}); err != nil {
 		return fmt.Errorf("eventsRepository.UpdateEvent: %w", err)
 	}
 
-	if _, err := s.eventsRepository.CreateEvent(ctx, tx, &model.Event{
-		RepeatRule: "",
-		Exceptions: map[int64]struct{}{},
-		Until:      &info.To,
+	for _, c := range changes {
+		if err := s.notifyGroup(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) UpdateEventFollowing(ctx context.Context, userID int64, id int64, version int64, ts time.Time, info *model.EventUpdate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	if err := s.updateEventFollowing(ctx, tx, userID, oldEvent, ts, info); err != nil {
+		return err
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) updateEventFollowing(ctx context.Context, q database.Queryable, userID int64, oldEvent *model.Event, ts time.Time, info *model.EventUpdate) error {
+	if oldEvent.RepeatType == model.RepeatTypeNone || !ts.After(oldEvent.From) {
+		return s.updateEvent(ctx, q, userID, oldEvent, ts, info)
+	}
+
+	recurrence, err := parseRecurrence(oldEvent.RepeatRule)
+	if err != nil {
+		return err
+	}
+
+	oldLoc, err := loadLocation(oldEvent.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	newLoc := oldLoc
+	if info.TimeZone != "" {
+		newLoc, err = loadLocation(info.TimeZone)
+		if err != nil {
+			return err
+		}
+	}
+
+	leftRecurrence, rightRecurrence, err := splitRecurrence(recurrence, oldEvent.From, ts, oldLoc)
+	if err != nil {
+		return err
+	}
+
+	if leftRecurrence == nil {
+		return s.updateEvent(ctx, q, userID, oldEvent, ts, info)
+	}
+
+	leftRule, err := getRule(leftRecurrence, oldEvent.From, oldLoc)
+	if err != nil {
+		return err
+	}
+
+	rightRule, err := getRule(applyRepeatEnd(rightRecurrence, info.RepeatEnd), info.From, newLoc)
+	if err != nil {
+		return err
+	}
+
+	leftEndDate, err := getEndDate(leftRule, oldEvent.From, oldEvent.To, oldLoc)
+	if err != nil {
+		return err
+	}
+
+	rightEndDate, err := getEndDate(rightRule, info.From, info.To, newLoc)
+	if err != nil {
+		return err
+	}
+
+	leftExceptions, rightExceptions := splitExceptions(oldEvent.Exceptions, ts)
+	rightExceptions = shiftExceptions(rightExceptions, oldLoc, ts, info.From, newLoc)
+
+	id, err := strconv.ParseInt(oldEvent.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse event id %q: %w", oldEvent.ID, err)
+	}
+
+	overrides, err := s.eventsRepository.GetOverrides(ctx, q, model.OverridesFilter{EventIDs: []int64{id}})
+	if err != nil {
+		return fmt.Errorf("eventsRepository.GetOverrides: %w", err)
+	}
+
+	var rightOverrides []*model.EventOverride
+	for _, o := range overrides {
+		if !o.OriginalStart.Before(ts) {
+			rightOverrides = append(rightOverrides, o)
+		}
+	}
+
+	// absolute reminders are split between the series by the time they fire
+	leftEvent := oldEvent.EventCreate
+	leftEvent.NotifyAt, _ = splitTimes(oldEvent.NotifyAt, ts)
+	_, rightNotifyAt := splitTimes(info.NotifyAt, ts)
+
+	if err := s.eventsRepository.UpdateEvent(ctx, q, &model.Event{
+		ID:          oldEvent.ID,
+		RepeatRule:  leftRule,
+		Exceptions:  leftExceptions,
+		Until:       leftEndDate,
+		UpdatedBy:   userID,
+		EventCreate: leftEvent,
+	}); err != nil {
+		return fmt.Errorf("eventsRepository.UpdateEvent: %w", err)
+	}
+
+	rightID, err := s.eventsRepository.CreateEvent(ctx, q, &model.Event{
+		RepeatRule: rightRule,
+		Exceptions: rightExceptions,
+		Until:      rightEndDate,
+		CreatorID:  oldEvent.CreatorID,
+		UpdatedBy:  userID,
 		EventCreate: model.EventCreate{
 			GroupID:       info.GroupID,
 			EventType:     info.EventType,
@@ -101,16 +377,134 @@ func (s *Service) UpdateEventInstance(ctx context.Context, id int64, ts time.Tim
 			AllDay:        info.AllDay,
 			From:          info.From,
 			To:            info.To,
-			RepeatType:    model.RepeatTypeNone,
+			TimeZone:      newLoc.String(),
+			RepeatType:    oldEvent.RepeatType,
 			Notifications: info.Notifications,
+			NotifyAt:      rightNotifyAt,
 			Attachments:   oldEvent.Attachments,
 		},
-	}); err != nil {
+	})
+	if err != nil {
 		return fmt.Errorf("eventsRepository.CreateEvent: %w", err)
 	}
 
+	if err := s.remindersRepository.SplitReminders(ctx, q, id, rightID, ts); err != nil {
+		return fmt.Errorf("remindersRepository.SplitReminders: %w", err)
+	}
+
+	if err := s.refreshSchedule(ctx, q, rightID, time.Now()); err != nil {
+		return err
+	}
+
+	if rescheduled(oldEvent, ts, info.From, info.To) {
+		if err := s.notifyGroup(ctx, q, &model.GroupChange{
+			GroupID: info.GroupID,
+			ActorID: userID,
+			Type:    model.ChangeTypeEventRescheduled,
+			EventID: instanceID(rightID, info.From),
+			Title:   info.Title,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return s.moveOverrides(ctx, q, userID, rightOverrides, rightID, oldEvent, info, oldLoc, ts, newLoc)
+}
+
+// moveOverrides reattaches overrides to the updated series, that is stored with eventID.
+func (s *Service) moveOverrides(
+	ctx context.Context,
+	q database.Queryable,
+	userID int64,
+	overrides []*model.EventOverride,
+	eventID int64,
+	oldEvent *model.Event,
+	info *model.EventUpdate,
+	oldLoc *time.Location,
+	ts time.Time,
+	newLoc *time.Location,
+) error {
+	for _, o := range overrides {
+		if err := s.eventsRepository.DeleteOverride(ctx, q, o.EventID, o.OriginalStart); err != nil {
+			return fmt.Errorf("eventsRepository.DeleteOverride: %w", err)
+		}
+	}
+
+	for _, o := range overrides {
+		originalStart := shiftWallClock(o.OriginalStart, oldLoc, ts, info.From, newLoc)
+		rebased := rebaseOverride(o, eventID, originalStart, oldEvent, info)
+		rebased.UpdatedBy = userID
+
+		if err := s.eventsRepository.UpsertOverride(ctx, q, rebased); err != nil {
+			return fmt.Errorf("eventsRepository.UpsertOverride: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceSeries overwrites the whole series including its exceptions and overrides,
+// it is used by clients that store events as iCalendar objects.
+func (s *Service) ReplaceSeries(ctx context.Context, userID int64, id int64, version int64, info *model.ImportedEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	oldEvent, err := s.lockEvent(ctx, tx, id, version)
+	if err != nil {
+		return err
+	}
+
+	eventInfo := info.EventCreate
+	eventInfo.GroupID = oldEvent.GroupID
+
+	event, err := newEvent(userID, &eventInfo)
+	if err != nil {
+		return err
+	}
+	event.ID = oldEvent.ID
+	if info.Exceptions != nil {
+		event.Exceptions = info.Exceptions
+	}
+
+	if err := s.eventsRepository.UpdateEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("eventsRepository.UpdateEvent: %w", err)
+	}
+
+	if err := s.eventsRepository.DeleteOverrides(ctx, tx, id, time.Time{}); err != nil {
+		return fmt.Errorf("eventsRepository.DeleteOverrides: %w", err)
+	}
+
+	for _, o := range info.Overrides {
+		override := *o
+		override.EventID = id
+		override.UpdatedBy = userID
+
+		if err := s.eventsRepository.UpsertOverride(ctx, tx, &override); err != nil {
+			return fmt.Errorf("eventsRepository.UpsertOverride: %w", err)
+		}
+	}
+
+	if rescheduled(oldEvent, oldEvent.From, event.From, event.To) {
+		if err := s.notifyGroup(ctx, tx, &model.GroupChange{
+			GroupID: event.GroupID,
+			ActorID: userID,
+			Type:    model.ChangeTypeEventRescheduled,
+			EventID: instanceID(id, event.From),
+			Title:   event.Title,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.refreshSchedule(ctx, tx, id, time.Now()); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit tx")
+		return fmt.Errorf("commit tx: %w", err)
 	}
 
 	return nil
@@ -21,6 +21,34 @@ type config struct {
 	ClientSecretPath     string        `env:"CLIENT_SECRET_PATH" envDefault:"secrets/client_secret.json"`
 	RedirectURL          string        `env:"REDIRECT_URL" envDefault:""`
 	MaxFileSize          int64         `env:"MAX_FILE_SIZE" envDefault:"5242880"`
+	PublicURL            string        `env:"PUBLIC_URL" envDefault:""`
+	FeedTokenLength      int           `env:"FEED_TOKEN_LENGTH" envDefault:"32"`
+	AppPasswordLength    int           `env:"APP_PASSWORD_LENGTH" envDefault:"24"`
+	AppPasswordTouchTTL  time.Duration `env:"APP_PASSWORD_TOUCH_TTL" envDefault:"1h"`
+	InviteCodeLength     int           `env:"INVITE_CODE_LENGTH" envDefault:"12"`
+	InviteTTL            time.Duration `env:"INVITE_TTL" envDefault:"168h"`
+	MaxInviteLinkTTL     time.Duration `env:"MAX_INVITE_LINK_TTL" envDefault:"720h"`
+	MaxNotifyOffset      time.Duration `env:"MAX_NOTIFY_OFFSET" envDefault:"672h"`
+	NotifyGracePeriod    time.Duration `env:"NOTIFY_GRACE_PERIOD" envDefault:"15m"`
+	NotifyClaimTimeout   time.Duration `env:"NOTIFY_CLAIM_TIMEOUT" envDefault:"5m"`
+	NotifyRetention      time.Duration `env:"NOTIFY_RETENTION" envDefault:"168h"`
+	ChangeNotifyDelay    time.Duration `env:"CHANGE_NOTIFY_DELAY" envDefault:"1m"`
+	ChangeNotifyMaxDelay time.Duration `env:"CHANGE_NOTIFY_MAX_DELAY" envDefault:"10m"`
+	InboxRetention       time.Duration `env:"INBOX_RETENTION" envDefault:"720h"`
+	ChangesRetention     time.Duration `env:"CHANGES_RETENTION" envDefault:"720h"`
+	PushProvider         string        `env:"PUSH_PROVIDER" envDefault:"fcm"`
+	NotifyLogPath        string        `env:"NOTIFY_LOG_PATH" envDefault:""`
+	SMTPHost             string        `env:"SMTP_HOST" envDefault:""`
+	SMTPPort             int           `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername         string        `env:"SMTP_USERNAME" envDefault:""`
+	SMTPPassword         string        `env:"SMTP_PASSWORD" envDefault:""`
+	SMTPFrom             string        `env:"SMTP_FROM" envDefault:""`
+	WebhooksEnabled      bool          `env:"WEBHOOKS_ENABLED" envDefault:"false"`
+	WebhookSecret        string        `env:"WEBHOOK_SECRET" envDefault:""`
+	SMSGatewayURL        string        `env:"SMS_GATEWAY_URL" envDefault:""`
+	SMSGatewayToken      string        `env:"SMS_GATEWAY_TOKEN" envDefault:""`
+	SMSFrom              string        `env:"SMS_FROM" envDefault:""`
+	ChannelTimeout       time.Duration `env:"CHANNEL_TIMEOUT" envDefault:"10s"`
 }
 
 var conf config
@@ -82,3 +110,115 @@ func RedirectURL() string {
 func MaxFileSize() int64 {
 	return conf.MaxFileSize
 }
+
+func PublicURL() string {
+	return conf.PublicURL
+}
+
+func FeedTokenLength() int {
+	return conf.FeedTokenLength
+}
+
+func AppPasswordLength() int {
+	return conf.AppPasswordLength
+}
+
+func AppPasswordTouchTTL() time.Duration {
+	return conf.AppPasswordTouchTTL
+}
+
+func InviteCodeLength() int {
+	return conf.InviteCodeLength
+}
+
+func InviteTTL() time.Duration {
+	return conf.InviteTTL
+}
+
+func MaxInviteLinkTTL() time.Duration {
+	return conf.MaxInviteLinkTTL
+}
+
+func MaxNotifyOffset() time.Duration {
+	return conf.MaxNotifyOffset
+}
+
+func NotifyGracePeriod() time.Duration {
+	return conf.NotifyGracePeriod
+}
+
+func NotifyClaimTimeout() time.Duration {
+	return conf.NotifyClaimTimeout
+}
+
+func NotifyRetention() time.Duration {
+	return conf.NotifyRetention
+}
+
+func ChangeNotifyDelay() time.Duration {
+	return conf.ChangeNotifyDelay
+}
+
+func ChangeNotifyMaxDelay() time.Duration {
+	return conf.ChangeNotifyMaxDelay
+}
+
+func InboxRetention() time.Duration {
+	return conf.InboxRetention
+}
+
+func ChangesRetention() time.Duration {
+	return conf.ChangesRetention
+}
+
+func PushProvider() string {
+	return conf.PushProvider
+}
+
+func NotifyLogPath() string {
+	return conf.NotifyLogPath
+}
+
+func SMTPHost() string {
+	return conf.SMTPHost
+}
+
+func SMTPPort() int {
+	return conf.SMTPPort
+}
+
+func SMTPUsername() string {
+	return conf.SMTPUsername
+}
+
+func SMTPPassword() string {
+	return conf.SMTPPassword
+}
+
+func SMTPFrom() string {
+	return conf.SMTPFrom
+}
+
+func WebhooksEnabled() bool {
+	return conf.WebhooksEnabled
+}
+
+func WebhookSecret() string {
+	return conf.WebhookSecret
+}
+
+func SMSGatewayURL() string {
+	return conf.SMSGatewayURL
+}
+
+func SMSGatewayToken() string {
+	return conf.SMSGatewayToken
+}
+
+func SMSFrom() string {
+	return conf.SMSFrom
+}
+
+func ChannelTimeout() time.Duration {
+	return conf.ChannelTimeout
+}
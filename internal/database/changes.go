@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// LogChange appends the change to the log, that is used by clients to sync their state.
+// It must be called in the same transaction as the change itself.
+func LogChange(ctx context.Context, q Queryable, change *model.Change) error {
+	var userID interface{}
+	if change.UserID != 0 {
+		userID = change.UserID
+	}
+
+	qb := PSQL.
+		Insert(ChangesTable).
+		Columns("entity_type", "entity_id", "group_id", "user_id").
+		Values(change.EntityType, change.EntityID, change.GroupID, userID)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+)
+
+// DeleteChanges removes changes made before the given time. The horizon is moved first, so that
+// sync tokens, changes of which may be gone, are never served from the log.
+func (*Repository) DeleteChanges(ctx context.Context, q database.Queryable, before time.Time) error {
+	horizon := database.PSQL.
+		Insert(database.HorizonTable).
+		Columns("tx_id").
+		Select(
+			sq.Select("max(tx_id)").
+				From(database.ChangesTable).
+				Where(sq.Lt{"created_at": before}).
+				Having("count(*) <> 0"),
+		).
+		Suffix("on conflict (id) do update set tx_id = greatest(changes_horizon.tx_id, excluded.tx_id)")
+
+	if _, err := q.Exec(ctx, horizon); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	qb := database.PSQL.
+		Delete(database.ChangesTable).
+		Where("tx_id <= (select tx_id from changes_horizon)")
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package changes
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/model"
+
+type changeDTO struct {
+	EntityType int
+	EntityID   int64
+	GroupID    int64
+	UserID     int64
+}
+
+func mapToChange(d *changeDTO) *model.Change {
+	return &model.Change{
+		EntityType: model.EntityType(d.EntityType),
+		EntityID:   d.EntityID,
+		GroupID:    d.GroupID,
+		UserID:     d.UserID,
+	}
+}
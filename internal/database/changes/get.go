@@ -0,0 +1,67 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// GetSyncPoint returns the oldest transaction that is still in progress. All changes made
+// by transactions before it are already visible, later ones are returned by the next sync.
+func (*Repository) GetSyncPoint(ctx context.Context, q database.Queryable) (int64, error) {
+	qb := database.PSQL.
+		Select("pg_snapshot_xmin(pg_current_snapshot())::text::bigint")
+
+	var syncPoint int64
+	if err := q.Get(ctx, &syncPoint, qb); err != nil {
+		return 0, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return syncPoint, nil
+}
+
+// GetHorizon returns the newest transaction, changes of which were deleted, or zero if nothing was deleted yet.
+func (*Repository) GetHorizon(ctx context.Context, q database.Queryable) (int64, error) {
+	qb := database.PSQL.
+		Select("coalesce(max(tx_id), 0)").
+		From(database.HorizonTable)
+
+	var horizon int64
+	if err := q.Get(ctx, &horizon, qb); err != nil {
+		return 0, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return horizon, nil
+}
+
+func (*Repository) GetChanges(ctx context.Context, q database.Queryable, filter model.ChangesFilter) ([]*model.Change, error) {
+	qb := database.PSQL.
+		Select(
+			"entity_type",
+			"entity_id",
+			"group_id",
+			"coalesce(user_id, 0) user_id",
+		).
+		From(database.ChangesTable).
+		Where(sq.GtOrEq{"tx_id": filter.SyncPoint}).
+		Where(sq.Or{
+			sq.Eq{"group_id": filter.GroupIDs},
+			sq.Eq{"user_id": filter.UserID},
+		}).
+		OrderBy("id")
+
+	var dtos []*changeDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.Change, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToChange(d)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,8 @@
+package changes
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
@@ -0,0 +1,16 @@
+package devices
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"id",
+		"user_id",
+		"session_hash",
+		"push_token",
+		"platform",
+		"app_version",
+		"created_at",
+		"last_seen_at",
+	).
+	From(database.DevicesTable)
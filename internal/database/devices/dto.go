@@ -0,0 +1,36 @@
+package devices
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type deviceDTO struct {
+	ID          int64
+	UserID      int64
+	SessionHash *string
+	PushToken   string
+	Platform    string
+	AppVersion  string
+	CreatedAt   time.Time
+	LastSeenAt  time.Time
+}
+
+func mapToDevice(dto *deviceDTO) *model.Device {
+	var sessionHash string
+	if dto.SessionHash != nil {
+		sessionHash = *dto.SessionHash
+	}
+
+	return &model.Device{
+		ID:          dto.ID,
+		UserID:      dto.UserID,
+		SessionHash: sessionHash,
+		PushToken:   dto.PushToken,
+		Platform:    model.Platform(dto.Platform),
+		AppVersion:  dto.AppVersion,
+		CreatedAt:   dto.CreatedAt,
+		LastSeenAt:  dto.LastSeenAt,
+	}
+}
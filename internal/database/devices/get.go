@@ -0,0 +1,28 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) GetUsersDevices(ctx context.Context, q database.Queryable, userIDs []int64) ([]*model.Device, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"user_id": userIDs}).
+		OrderBy("user_id", "id")
+
+	var dtos []*deviceDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.Device, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToDevice(d)
+	}
+
+	return res, nil
+}
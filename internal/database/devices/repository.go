@@ -0,0 +1,8 @@
+package devices
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
@@ -0,0 +1,158 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// UpsertDevice registers the device of the session. The push token is removed from other sessions,
+// as it belongs to the device the session is used on now.
+func (*Repository) UpsertDevice(ctx context.Context, q database.Queryable, device *model.Device) error {
+	deleteQb := database.PSQL.
+		Delete(database.DevicesTable).
+		Where(sq.Eq{"push_token": device.PushToken}).
+		Where("session_hash is distinct from ?", device.SessionHash)
+
+	if _, err := q.Exec(ctx, deleteQb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	qb := database.PSQL.
+		Insert(database.DevicesTable).
+		Columns(
+			"user_id",
+			"session_hash",
+			"push_token",
+			"platform",
+			"app_version",
+		).
+		Values(
+			device.UserID,
+			device.SessionHash,
+			device.PushToken,
+			device.Platform,
+			device.AppVersion,
+		).
+		Suffix(`on conflict (session_hash) do update set
+			push_token = excluded.push_token,
+			platform = excluded.platform,
+			app_version = excluded.app_version,
+			last_seen_at = now()`)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertLegacyDevice registers the push token of the legacy client, which doesn't tell its session.
+// As before devices, the user has a single legacy token, the token is removed from other devices as well.
+func (*Repository) UpsertLegacyDevice(ctx context.Context, q database.Queryable, userID int64, pushToken string) error {
+	deleteQb := database.PSQL.
+		Delete(database.DevicesTable).
+		Where(sq.Or{
+			sq.Eq{"push_token": pushToken},
+			sq.Eq{"user_id": userID, "session_hash": nil},
+		})
+
+	if _, err := q.Exec(ctx, deleteQb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	qb := database.PSQL.
+		Insert(database.DevicesTable).
+		Columns(
+			"user_id",
+			"push_token",
+			"platform",
+		).
+		Values(
+			userID,
+			pushToken,
+			"",
+		)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// MoveDevice binds the device to the refreshed session.
+func (*Repository) MoveDevice(ctx context.Context, q database.Queryable, oldSessionHash string, newSessionHash string) error {
+	qb := database.PSQL.
+		Update(database.DevicesTable).
+		Set("session_hash", newSessionHash).
+		Set("last_seen_at", sq.Expr("now()")).
+		Where(sq.Eq{"session_hash": oldSessionHash})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteDevice(ctx context.Context, q database.Queryable, sessionHash string) error {
+	qb := database.PSQL.
+		Delete(database.DevicesTable).
+		Where(sq.Eq{"session_hash": sessionHash})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDevicesByTokens removes devices with tokens rejected by FCM.
+// DeleteLegacyDevices removes devices of the user without a session.
+func (*Repository) DeleteLegacyDevices(ctx context.Context, q database.Queryable, userID int64) error {
+	qb := database.PSQL.
+		Delete(database.DevicesTable).
+		Where(sq.Eq{"user_id": userID, "session_hash": nil})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteDevicesByTokens(ctx context.Context, q database.Queryable, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	qb := database.PSQL.
+		Delete(database.DevicesTable).
+		Where(sq.Eq{"push_token": tokens})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStaleDevices removes devices of sessions that were not refreshed since the given time, such sessions are expired.
+// Legacy devices have no session to expire.
+func (*Repository) DeleteStaleDevices(ctx context.Context, q database.Queryable, before time.Time) error {
+	qb := database.PSQL.
+		Delete(database.DevicesTable).
+		Where(sq.Lt{"last_seen_at": before}).
+		Where(sq.NotEq{"session_hash": nil})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
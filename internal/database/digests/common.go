@@ -0,0 +1,16 @@
+package digests
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"user_id",
+		"daily",
+		"daily_time",
+		"weekly",
+		"weekly_day",
+		"weekly_time",
+		"group_ids",
+		"email",
+	).
+	From(database.DigestsTable)
@@ -0,0 +1,31 @@
+package digests
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type settingsDTO struct {
+	UserID     int64
+	Daily      bool
+	DailyTime  int
+	Weekly     bool
+	WeeklyDay  int
+	WeeklyTime int
+	GroupIDs   []int64
+	Email      bool
+}
+
+func mapToSettings(dto *settingsDTO) *model.DigestSettings {
+	return &model.DigestSettings{
+		UserID:     dto.UserID,
+		Daily:      dto.Daily,
+		DailyTime:  dto.DailyTime,
+		Weekly:     dto.Weekly,
+		WeeklyDay:  time.Weekday(dto.WeeklyDay),
+		WeeklyTime: dto.WeeklyTime,
+		GroupIDs:   dto.GroupIDs,
+		Email:      dto.Email,
+	}
+}
@@ -0,0 +1,34 @@
+package digests
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) GetDigestSettings(ctx context.Context, q database.Queryable, filter model.DigestSettingsFilter) ([]*model.DigestSettings, error) {
+	qb := baseQuery
+
+	if len(filter.UserIDs) != 0 {
+		qb = qb.Where(sq.Eq{"user_id": filter.UserIDs})
+	}
+
+	if filter.Enabled {
+		qb = qb.Where("daily or weekly")
+	}
+
+	var dtos []*settingsDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.DigestSettings, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToSettings(d)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,8 @@
+package digests
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
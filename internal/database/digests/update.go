@@ -0,0 +1,54 @@
+package digests
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) UpsertDigestSettings(ctx context.Context, q database.Queryable, settings *model.DigestSettings) error {
+	groupIDs := settings.GroupIDs
+	if groupIDs == nil {
+		groupIDs = []int64{}
+	}
+
+	qb := database.PSQL.
+		Insert(database.DigestsTable).
+		Columns(
+			"user_id",
+			"daily",
+			"daily_time",
+			"weekly",
+			"weekly_day",
+			"weekly_time",
+			"group_ids",
+			"email",
+		).
+		Values(
+			settings.UserID,
+			settings.Daily,
+			settings.DailyTime,
+			settings.Weekly,
+			int(settings.WeeklyDay),
+			settings.WeeklyTime,
+			groupIDs,
+			settings.Email,
+		).
+		Suffix(`on conflict (user_id) do update set
+			daily = excluded.daily,
+			daily_time = excluded.daily_time,
+			weekly = excluded.weekly,
+			weekly_day = excluded.weekly_day,
+			weekly_time = excluded.weekly_time,
+			group_ids = excluded.group_ids,
+			email = excluded.email,
+			updated_at = now()`)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
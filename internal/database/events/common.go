@@ -9,13 +9,21 @@ var baseQuery = database.PSQL.
 		"description",
 		"attachments",
 		"notifications",
+		"notify_at",
 		"group_id",
 		"all_day",
 		"repeat_type",
 		"start_date",
 		"end_date",
 		"duration",
+		"time_zone",
 		"recurrence_rule",
 		"exceptions",
+		"creator_id",
+		"updated_by",
+		"created_at",
+		"updated_at",
+		"version",
+		"coalesce(uid, '') uid",
 	).
 	From(database.EventsTable)
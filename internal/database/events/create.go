@@ -2,18 +2,34 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgconn"
 )
 
+const uniqueViolationCode = "23505"
+
+// CreateEvent returns ErrAlreadyExists if the event with the same UID was already imported to the group.
 func (*Repository) CreateEvent(ctx context.Context, q database.Queryable, event *model.Event) (int64, error) {
 	notifications := make([]int64, len(event.Notifications))
 	for i, n := range event.Notifications {
 		notifications[i] = int64(n)
 	}
 
+	exceptions := make([]time.Time, 0, len(event.Exceptions))
+	for e := range event.Exceptions {
+		exceptions = append(exceptions, time.Unix(e, 0))
+	}
+
+	var uid *string
+	if event.UID != "" {
+		uid = &event.UID
+	}
+
 	qb := database.PSQL.
 		Insert(database.EventsTable).
 		Columns(
@@ -22,34 +38,58 @@ func (*Repository) CreateEvent(ctx context.Context, q database.Queryable, event
 			"description",
 			"attachments",
 			"notifications",
+			"notify_at",
 			"group_id",
 			"all_day",
 			"repeat_type",
 			"start_date",
 			"end_date",
 			"duration",
+			"time_zone",
 			"recurrence_rule",
+			"exceptions",
+			"creator_id",
+			"updated_by",
+			"uid",
 		).
 		Values(
 			event.EventType,
 			event.Title,
 			event.Description,
 			event.Attachments,
-			event.Notifications,
+			notifications,
+			event.NotifyAt,
 			event.GroupID,
 			event.AllDay,
 			event.RepeatType,
 			event.From,
 			event.Until,
 			event.To.Sub(event.From),
+			event.TimeZone,
 			event.RepeatRule,
+			exceptions,
+			event.CreatorID,
+			event.UpdatedBy,
+			uid,
 		).
 		Suffix("returning id")
 
 	var id int64
 	if err := q.Get(ctx, &id, qb); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return 0, model.ErrAlreadyExists
+		}
 		return 0, fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeEvent,
+		EntityID:   id,
+		GroupID:    event.GroupID,
+	}); err != nil {
+		return 0, fmt.Errorf("log change: %w", err)
+	}
+
 	return id, nil
 }
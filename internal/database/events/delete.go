@@ -2,20 +2,36 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgx/v4"
 )
 
 func (*Repository) DeleteEvent(ctx context.Context, q database.Queryable, id int64) error {
 	qb := database.PSQL.
 		Delete(database.EventsTable).
-		Where(sq.Eq{"id": id})
+		Where(sq.Eq{"id": id}).
+		Suffix("returning group_id")
 
-	if _, err := q.Exec(ctx, qb); err != nil {
+	var groupID int64
+	if err := q.Get(ctx, &groupID, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
 		return fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeEvent,
+		EntityID:   id,
+		GroupID:    groupID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
 	return nil
 }
@@ -14,14 +14,22 @@ type eventDTO struct {
 	Description    string
 	Attachments    []*attachmentDTO
 	Notifications  []int64
+	NotifyAt       []time.Time
 	GroupID        int64
 	AllDay         bool
 	RepeatType     int
 	StartDate      time.Time
 	EndDate        *time.Time
 	Duration       time.Duration
+	TimeZone       string
 	RecurrenceRule string
 	Exceptions     []time.Time
+	CreatorID      int64
+	UpdatedBy      int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Version        int64
+	UID            string
 }
 
 type attachmentDTO struct {
@@ -53,6 +61,11 @@ func mapToEvent(dto *eventDTO) *model.Event {
 		RepeatRule: dto.RecurrenceRule,
 		Exceptions: exceptions,
 		Until:      dto.EndDate,
+		CreatorID:  dto.CreatorID,
+		UpdatedBy:  dto.UpdatedBy,
+		CreatedAt:  dto.CreatedAt,
+		UpdatedAt:  dto.UpdatedAt,
+		Version:    dto.Version,
 		EventCreate: model.EventCreate{
 			GroupID:       dto.GroupID,
 			EventType:     model.EventType(dto.EventType),
@@ -61,9 +74,57 @@ func mapToEvent(dto *eventDTO) *model.Event {
 			AllDay:        dto.AllDay,
 			From:          dto.StartDate,
 			To:            dto.StartDate.Add(dto.Duration),
+			TimeZone:      dto.TimeZone,
 			RepeatType:    model.RepeatType(dto.RepeatType),
 			Notifications: notifications,
+			NotifyAt:      dto.NotifyAt,
 			Attachments:   attachments,
+			UID:           dto.UID,
 		},
 	}
 }
+
+type overrideDTO struct {
+	EventID       int64
+	OriginalStart time.Time
+	EventType     int `db:"type"`
+	Title         string
+	Description   string
+	Attachments   []*attachmentDTO
+	Notifications []int64
+	AllDay        bool
+	StartDate     time.Time
+	Duration      time.Duration
+	UpdatedBy     int64
+	UpdatedAt     time.Time
+}
+
+func mapToOverride(dto *overrideDTO) *model.EventOverride {
+	notifications := make([]time.Duration, len(dto.Notifications))
+	for i, n := range dto.Notifications {
+		notifications[i] = time.Duration(n)
+	}
+
+	attachments := make([]*model.Attachment, len(dto.Attachments))
+	for i, a := range dto.Attachments {
+		attachments[i] = &model.Attachment{
+			Name: a.Name,
+			Path: a.Path,
+		}
+	}
+
+	return &model.EventOverride{
+		EventID:       dto.EventID,
+		OriginalStart: dto.OriginalStart,
+		EventType:     model.EventType(dto.EventType),
+		Title:         dto.Title,
+		Description:   dto.Description,
+		AllDay:        dto.AllDay,
+		From:          dto.StartDate,
+		To:            dto.StartDate.Add(dto.Duration),
+		Notifications: notifications,
+		Attachments:   attachments,
+		UpdatedBy:     dto.UpdatedBy,
+		UpdatedAt:     dto.UpdatedAt,
+	}
+}
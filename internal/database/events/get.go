@@ -26,16 +26,60 @@ func (*Repository) GetEventByID(ctx context.Context, q database.Queryable, id in
 	return mapToEvent(dto), nil
 }
 
+// GetEventByIDForUpdate reads the event and locks its row until the end of the transaction.
+func (*Repository) GetEventByIDForUpdate(ctx context.Context, q database.Queryable, id int64) (*model.Event, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"id": id}).
+		Suffix("for update")
+
+	dto := &eventDTO{}
+	if err := q.Get(ctx, dto, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNoRecord
+		}
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return mapToEvent(dto), nil
+}
+
 func (*Repository) GetEvents(ctx context.Context, q database.Queryable, filter model.EventsFilter) ([]*model.Event, error) {
 	qb := baseQuery.
-		Where(sq.LtOrEq{"start_date": filter.To}).
-		Where(sq.Or{sq.Eq{"end_date": nil}, sq.Gt{"end_date": filter.From}}).
 		OrderBy("id")
 
+	if !filter.From.IsZero() && !filter.To.IsZero() {
+		// instances can be moved out of the series span, so overrides are matched by their own dates
+		qb = qb.
+			Where(sq.Or{
+				sq.And{
+					sq.LtOrEq{"start_date": filter.To},
+					sq.Or{sq.Eq{"end_date": nil}, sq.Gt{"end_date": filter.From}},
+				},
+				sq.Expr("exists (select from "+database.OverridesTable+" o where o.event_id = "+database.EventsTable+".id"+
+					" and o.start_date <= ? and o.start_date + o.duration >= ?)", filter.To, filter.From),
+			})
+	}
+
+	if len(filter.IDs) != 0 {
+		qb = qb.Where(sq.Eq{"id": filter.IDs})
+	}
+
 	if len(filter.GroupIDs) != 0 {
 		qb = qb.Where(sq.Eq{"group_id": filter.GroupIDs})
 	}
 
+	if len(filter.CreatorIDs) != 0 {
+		qb = qb.Where(sq.Eq{"creator_id": filter.CreatorIDs})
+	}
+
+	if len(filter.UIDs) != 0 {
+		qb = qb.Where(sq.Eq{"uid": filter.UIDs})
+	}
+
+	if !filter.NotifyFrom.IsZero() && !filter.NotifyTo.IsZero() {
+		qb = qb.Where("exists (select from unnest(notify_at) t where t >= ? and t < ?)", filter.NotifyFrom, filter.NotifyTo)
+	}
+
 	var dtos []*eventDTO
 	if err := q.Select(ctx, &dtos, qb); err != nil {
 		return nil, fmt.Errorf("SQL request: %w", err)
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+var overridesQuery = database.PSQL.
+	Select(
+		"o.event_id",
+		"o.original_start",
+		"o.type",
+		"o.title",
+		"o.description",
+		"o.attachments",
+		"o.notifications",
+		"o.all_day",
+		"o.start_date",
+		"o.duration",
+		"o.updated_by",
+		"o.updated_at",
+	).
+	From(database.OverridesTable + " o")
+
+func (*Repository) GetOverrides(ctx context.Context, q database.Queryable, filter model.OverridesFilter) ([]*model.EventOverride, error) {
+	qb := overridesQuery.
+		Where(sq.Eq{"o.event_id": filter.EventIDs}).
+		OrderBy("o.event_id", "o.original_start")
+
+	if !filter.From.IsZero() && !filter.To.IsZero() {
+		// either replaced occurrence or the override itself intersects the interval
+		qb = qb.
+			Join(database.EventsTable + " e on e.id = o.event_id").
+			Where(sq.Or{
+				sq.And{
+					sq.LtOrEq{"o.original_start": filter.To},
+					sq.Expr("o.original_start + e.duration >= ?", filter.From),
+				},
+				sq.And{
+					sq.LtOrEq{"o.start_date": filter.To},
+					sq.Expr("o.start_date + o.duration >= ?", filter.From),
+				},
+			})
+	}
+
+	var dtos []*overrideDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.EventOverride, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToOverride(d)
+	}
+
+	return res, nil
+}
+
+func (*Repository) UpsertOverride(ctx context.Context, q database.Queryable, override *model.EventOverride) error {
+	notifications := make([]int64, len(override.Notifications))
+	for i, n := range override.Notifications {
+		notifications[i] = int64(n)
+	}
+
+	qb := database.PSQL.
+		Insert(database.OverridesTable).
+		Columns(
+			"event_id",
+			"original_start",
+			"type",
+			"title",
+			"description",
+			"attachments",
+			"notifications",
+			"all_day",
+			"start_date",
+			"duration",
+			"updated_by",
+		).
+		Values(
+			override.EventID,
+			override.OriginalStart,
+			override.EventType,
+			override.Title,
+			override.Description,
+			override.Attachments,
+			notifications,
+			override.AllDay,
+			override.From,
+			override.To.Sub(override.From),
+			override.UpdatedBy,
+		).
+		Suffix(`on conflict (event_id, original_start) do update set
+			type = excluded.type,
+			title = excluded.title,
+			description = excluded.description,
+			attachments = excluded.attachments,
+			notifications = excluded.notifications,
+			all_day = excluded.all_day,
+			start_date = excluded.start_date,
+			duration = excluded.duration,
+			updated_by = excluded.updated_by,
+			updated_at = now()`)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteOverrides deletes overrides of the series starting from the given original start.
+func (*Repository) DeleteOverrides(ctx context.Context, q database.Queryable, eventID int64, from time.Time) error {
+	qb := database.PSQL.
+		Delete(database.OverridesTable).
+		Where(sq.Eq{"event_id": eventID}).
+		Where(sq.GtOrEq{"original_start": from})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteOverride(ctx context.Context, q database.Queryable, eventID int64, originalStart time.Time) error {
+	qb := database.PSQL.
+		Delete(database.OverridesTable).
+		Where(sq.Eq{"event_id": eventID, "original_start": originalStart})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package events
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -21,6 +22,22 @@ func (*Repository) UpdateEvent(ctx context.Context, q database.Queryable, event
 		exceptions = append(exceptions, time.Unix(e, 0))
 	}
 
+	// members of the old group have to know that the event was moved out of it
+	movedQb := database.PSQL.
+		Insert(database.ChangesTable).
+		Columns("entity_type", "entity_id", "group_id").
+		Select(database.PSQL.
+			Select().
+			Column(sq.Expr("?::smallint", model.EntityTypeEvent)).
+			Columns("id", "group_id").
+			From(database.EventsTable).
+			Where(sq.Eq{"id": event.ID}).
+			Where(sq.NotEq{"group_id": event.GroupID}))
+
+	if _, err := q.Exec(ctx, movedQb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
 	qb := database.PSQL.
 		Update(database.EventsTable).
 		SetMap(map[string]interface{}{
@@ -29,14 +46,19 @@ func (*Repository) UpdateEvent(ctx context.Context, q database.Queryable, event
 			"description":     event.Description,
 			"attachments":     event.Attachments,
 			"notifications":   notifications,
+			"notify_at":       event.NotifyAt,
 			"group_id":        event.GroupID,
 			"all_day":         event.AllDay,
 			"repeat_type":     event.RepeatType,
 			"start_date":      event.From,
 			"end_date":        event.Until,
 			"duration":        event.To.Sub(event.From),
+			"time_zone":       event.TimeZone,
 			"recurrence_rule": event.RepeatRule,
 			"exceptions":      exceptions,
+			"updated_by":      event.UpdatedBy,
+			"updated_at":      sq.Expr("now()"),
+			"version":         sq.Expr("version + 1"),
 		}).
 		Where(sq.Eq{"id": event.ID})
 
@@ -44,5 +66,18 @@ func (*Repository) UpdateEvent(ctx context.Context, q database.Queryable, event
 		return fmt.Errorf("SQL request: %w", err)
 	}
 
+	id, err := strconv.ParseInt(event.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse event id %q: %w", event.ID, err)
+	}
+
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeEvent,
+		EntityID:   id,
+		GroupID:    event.GroupID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,11 @@
+package feeds
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"token",
+		"user_id",
+		"coalesce(group_id, 0) group_id",
+	).
+	From(database.FeedsTable)
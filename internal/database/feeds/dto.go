@@ -0,0 +1,17 @@
+package feeds
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/model"
+
+type feedDTO struct {
+	Token   string
+	UserID  int64
+	GroupID int64
+}
+
+func mapToFeed(d *feedDTO) *model.Feed {
+	return &model.Feed{
+		Token:   d.Token,
+		UserID:  d.UserID,
+		GroupID: d.GroupID,
+	}
+}
@@ -0,0 +1,45 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgx/v4"
+)
+
+func (*Repository) GetFeedByToken(ctx context.Context, q database.Queryable, token string) (*model.Feed, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"token": token})
+
+	dto := &feedDTO{}
+	if err := q.Get(ctx, dto, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNoRecord
+		}
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return mapToFeed(dto), nil
+}
+
+func (*Repository) GetUserFeeds(ctx context.Context, q database.Queryable, userID int64) ([]*model.Feed, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("id")
+
+	var dtos []*feedDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.Feed, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToFeed(d)
+	}
+
+	return res, nil
+}
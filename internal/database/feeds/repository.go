@@ -0,0 +1,8 @@
+package feeds
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
@@ -0,0 +1,51 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+// UpsertFeed creates the feed or replaces the token of the existing one, so that the old link stops working.
+func (*Repository) UpsertFeed(ctx context.Context, q database.Queryable, feed *model.Feed) error {
+	var groupID interface{}
+	if feed.GroupID != 0 {
+		groupID = feed.GroupID
+	}
+
+	qb := database.PSQL.
+		Insert(database.FeedsTable).
+		Columns("token", "user_id", "group_id").
+		Values(feed.Token, feed.UserID, groupID).
+		Suffix("on conflict (user_id, coalesce(group_id, 0)) do update set token = excluded.token, created_at = now()")
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return model.ErrAlreadyExists
+		}
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteFeed(ctx context.Context, q database.Queryable, userID int64, groupID int64) error {
+	qb := database.PSQL.
+		Delete(database.FeedsTable).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Expr("coalesce(group_id, 0) = ?", groupID))
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -9,7 +9,9 @@ var baseQuery = database.PSQL.
 		"g.id",
 		"g.name",
 		"g.creator_id",
-		"array_agg(ug.user_id) users_ids",
+		"g.version",
+		"array_agg(ug.user_id order by ug.id) users_ids",
+		"array_agg(ug.role order by ug.id) roles",
 	).
 	From(database.GroupsTable + " g").
 	Join(database.UserGroupTable + " ug on g.id = ug.group_id").
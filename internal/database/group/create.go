@@ -20,5 +20,13 @@ func (*Repository) CreateGroup(ctx context.Context, q database.Queryable, group
 		return 0, fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeGroup,
+		EntityID:   id,
+		GroupID:    id,
+	}); err != nil {
+		return 0, fmt.Errorf("log change: %w", err)
+	}
+
 	return id, nil
 }
@@ -11,13 +11,22 @@ type groupDTO struct {
 	ID        int64
 	Name      string
 	CreatorID int64
+	Version   int64
 	UsersIDs  []int64 `db:"users_ids"`
+	Roles     []string
 }
 
 func mapToGroup(d *groupDTO) *model.Group {
+	roles := make(map[int64]model.GroupRole, len(d.UsersIDs))
+	for i, id := range d.UsersIDs {
+		roles[id] = model.GroupRole(d.Roles[i])
+	}
+
 	return &model.Group{
 		ID:       d.ID,
 		UsersIDs: d.UsersIDs,
+		Roles:    roles,
+		Version:  d.Version,
 		GroupCreate: model.GroupCreate{
 			Name:      d.Name,
 			CreatorID: d.CreatorID,
@@ -26,6 +26,25 @@ func (*Repository) GetGroup(ctx context.Context, q database.Queryable, id int64)
 	return mapToGroup(dto), nil
 }
 
+// LockGroup locks the group row until the end of the transaction and returns its current version.
+func (*Repository) LockGroup(ctx context.Context, q database.Queryable, id int64) (int64, error) {
+	qb := database.PSQL.
+		Select("version").
+		From(database.GroupsTable).
+		Where(sq.Eq{"id": id}).
+		Suffix("for update")
+
+	var version int64
+	if err := q.Get(ctx, &version, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, model.ErrNoRecord
+		}
+		return 0, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return version, nil
+}
+
 func (*Repository) GetGroups(ctx context.Context, q database.Queryable, ids []int64) ([]*model.Group, error) {
 	qb := baseQuery.
 		Where(sq.Eq{"g.id": ids})
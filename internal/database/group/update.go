@@ -13,12 +13,21 @@ func (*Repository) UpdateGroupName(ctx context.Context, q database.Queryable, gr
 	qb := database.PSQL.
 		Update(database.GroupsTable).
 		Set("name", name).
+		Set("version", sq.Expr("version + 1")).
 		Where(sq.Eq{"id": groupID})
 
 	if _, err := q.Exec(ctx, qb); err != nil {
 		return fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeGroup,
+		EntityID:   groupID,
+		GroupID:    groupID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
 	return nil
 }
 
@@ -33,24 +42,91 @@ func (*Repository) UpdateGroupSettings(ctx context.Context, q database.Queryable
 		return fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeMembership,
+		EntityID:   settings.UserID,
+		GroupID:    settings.GroupID,
+		UserID:     settings.UserID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
 	return nil
 }
 
+// AddUserToGroup adds the user with the role from settings, members join as editors if it is not set.
 func (*Repository) AddUserToGroup(ctx context.Context, q database.Queryable, settings *model.GroupSettings) error {
+	role := settings.Role
+	if role == "" {
+		role = model.GroupRoleEditor
+	}
+
 	qb := database.PSQL.
 		Insert(database.UserGroupTable).
-		Columns("user_id", "group_id", "color", "notify").
+		Columns("user_id", "group_id", "color", "notify", "role").
 		Values(
 			settings.UserID,
 			settings.GroupID,
 			"#"+settings.Color.ToHTML(),
 			settings.Notify,
+			role,
 		)
 
 	if _, err := q.Exec(ctx, qb); err != nil {
 		return fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeMembership,
+		EntityID:   settings.UserID,
+		GroupID:    settings.GroupID,
+		UserID:     settings.UserID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) UpdateUserRole(ctx context.Context, q database.Queryable, groupID int64, userID int64, role model.GroupRole) error {
+	qb := database.PSQL.
+		Update(database.UserGroupTable).
+		Set("role", role).
+		Where(sq.Eq{"group_id": groupID, "user_id": userID})
+
+	res, err := q.Exec(ctx, qb)
+	if err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return model.ErrNoRecord
+	}
+
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeMembership,
+		EntityID:   userID,
+		GroupID:    groupID,
+		UserID:     userID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
+	return nil
+}
+
+// IncrementGroupVersion marks the group as changed when its members change, so that members
+// calculated from the group read before are not applied over the change.
+func (*Repository) IncrementGroupVersion(ctx context.Context, q database.Queryable, groupID int64) error {
+	qb := database.PSQL.
+		Update(database.GroupsTable).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": groupID})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
 	return nil
 }
 
@@ -63,5 +139,14 @@ func (*Repository) RemoveUserFromGroup(ctx context.Context, q database.Queryable
 		return fmt.Errorf("SQL request: %w", err)
 	}
 
+	if err := database.LogChange(ctx, q, &model.Change{
+		EntityType: model.EntityTypeMembership,
+		EntityID:   userID,
+		GroupID:    groupID,
+		UserID:     userID,
+	}); err != nil {
+		return fmt.Errorf("log change: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,17 @@
+package inbox
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"id",
+		"notification_id",
+		"user_id",
+		"kind",
+		"group_id",
+		"event_id",
+		"data",
+		"read_at",
+		"created_at",
+	).
+	From(database.InboxTable)
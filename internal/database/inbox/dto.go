@@ -0,0 +1,38 @@
+package inbox
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type itemDTO struct {
+	ID             int64
+	NotificationID int64
+	UserID         int64
+	Kind           int
+	GroupID        *int64
+	EventID        string
+	Data           map[string]string
+	ReadAt         *time.Time
+	CreatedAt      time.Time
+}
+
+func mapToItem(dto *itemDTO) *model.InboxItem {
+	res := &model.InboxItem{
+		ID:             dto.ID,
+		NotificationID: dto.NotificationID,
+		UserID:         dto.UserID,
+		Kind:           model.NotificationKind(dto.Kind),
+		EventID:        dto.EventID,
+		Data:           dto.Data,
+		ReadAt:         dto.ReadAt,
+		CreatedAt:      dto.CreatedAt,
+	}
+
+	if dto.GroupID != nil {
+		res.GroupID = *dto.GroupID
+	}
+
+	return res
+}
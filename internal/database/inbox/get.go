@@ -0,0 +1,48 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) GetInboxItems(ctx context.Context, q database.Queryable, filter model.InboxFilter) ([]*model.InboxItem, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"user_id": filter.UserID}).
+		OrderBy("id desc").
+		Limit(filter.Limit)
+
+	if filter.BeforeID != 0 {
+		qb = qb.Where(sq.Lt{"id": filter.BeforeID})
+	}
+
+	var dtos []*itemDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.InboxItem, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToItem(d)
+	}
+
+	return res, nil
+}
+
+func (*Repository) CountUnread(ctx context.Context, q database.Queryable, userID int64) (int64, error) {
+	qb := database.PSQL.
+		Select("count(*)").
+		From(database.InboxTable).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"read_at": nil})
+
+	var count int64
+	if err := q.Get(ctx, &count, qb); err != nil {
+		return 0, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,8 @@
+package inbox
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
@@ -0,0 +1,100 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// insertBatchSize keeps the number of query parameters within the postgres limit.
+const insertBatchSize = 1000
+
+// AddInboxItems stores sent notifications, notifications that are already in the inbox are skipped.
+func (*Repository) AddInboxItems(ctx context.Context, q database.Queryable, items []*model.InboxItem) error {
+	for i := 0; i < len(items); i += insertBatchSize {
+		to := i + insertBatchSize
+		if to > len(items) {
+			to = len(items)
+		}
+
+		qb := database.PSQL.
+			Insert(database.InboxTable).
+			Columns(
+				"notification_id",
+				"user_id",
+				"kind",
+				"group_id",
+				"event_id",
+				"data",
+			).
+			Suffix("on conflict (notification_id) do nothing")
+
+		for _, item := range items[i:to] {
+			var groupID interface{}
+			if item.GroupID != 0 {
+				groupID = item.GroupID
+			}
+
+			qb = qb.Values(
+				item.NotificationID,
+				item.UserID,
+				item.Kind,
+				groupID,
+				item.EventID,
+				item.Data,
+			)
+		}
+
+		if _, err := q.Exec(ctx, qb); err != nil {
+			return fmt.Errorf("SQL request: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// MarkRead marks items of the user as read, items of other users are ignored.
+func (*Repository) MarkRead(ctx context.Context, q database.Queryable, userID int64, ids []int64) error {
+	qb := database.PSQL.
+		Update(database.InboxTable).
+		Set("read_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"id": ids}).
+		Where(sq.Eq{"read_at": nil})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) MarkAllRead(ctx context.Context, q database.Queryable, userID int64) error {
+	qb := database.PSQL.
+		Update(database.InboxTable).
+		Set("read_at", sq.Expr("now()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"read_at": nil})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteInboxItems(ctx context.Context, q database.Queryable, before time.Time) error {
+	qb := database.PSQL.
+		Delete(database.InboxTable).
+		Where(sq.Lt{"created_at": before})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
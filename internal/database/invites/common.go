@@ -0,0 +1,19 @@
+package invites
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var columns = []string{
+	"id",
+	"group_id",
+	"inviter_id",
+	"coalesce(user_id, 0) user_id",
+	"coalesce(code_hash, '') code_hash",
+	"role",
+	"expires_at",
+	"created_at",
+}
+
+var baseQuery = database.PSQL.
+	Select(columns...).
+	From(database.InvitesTable).
+	Where("expires_at > now()")
@@ -0,0 +1,43 @@
+package invites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+// CreateInvite creates the invite link or the personal invite. The pending personal invite of the user
+// to the same group is renewed with the new role and expiration time instead.
+func (*Repository) CreateInvite(ctx context.Context, q database.Queryable, invite *model.GroupInvite) (int64, error) {
+	var userID, codeHash interface{}
+	if invite.UserID != 0 {
+		userID = invite.UserID
+	} else {
+		codeHash = invite.CodeHash
+	}
+
+	qb := database.PSQL.
+		Insert(database.InvitesTable).
+		Columns("group_id", "inviter_id", "user_id", "code_hash", "role", "expires_at").
+		Values(invite.GroupID, invite.InviterID, userID, codeHash, invite.Role, invite.ExpiresAt).
+		Suffix(`on conflict (group_id, user_id) where user_id is not null do update
+			set inviter_id = excluded.inviter_id, role = excluded.role, expires_at = excluded.expires_at, created_at = now()
+			returning id`)
+
+	var id int64
+	if err := q.Get(ctx, &id, qb); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return 0, model.ErrAlreadyExists
+		}
+		return 0, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,68 @@
+package invites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgx/v4"
+)
+
+// TakeInvite deletes the pending invite of the group, or personal invite of the user if groupID is zero,
+// and returns it, so that it is used only once.
+func (*Repository) TakeInvite(ctx context.Context, q database.Queryable, id int64, groupID int64, userID int64) (*model.GroupInvite, error) {
+	qb := database.PSQL.
+		Delete(database.InvitesTable).
+		Where(sq.Eq{"id": id}).
+		Where("expires_at > now()").
+		Suffix("returning " + strings.Join(columns, ", "))
+
+	if groupID != 0 {
+		qb = qb.Where(sq.Eq{"group_id": groupID})
+	}
+
+	if userID != 0 {
+		qb = qb.Where(sq.Eq{"user_id": userID})
+	}
+
+	dto := &inviteDTO{}
+	if err := q.Get(ctx, dto, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNoRecord
+		}
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return mapToInvite(dto), nil
+}
+
+// DeleteUserInvites deletes personal invites of the user to the group once the user has joined it.
+func (*Repository) DeleteUserInvites(ctx context.Context, q database.Queryable, groupID int64, userID int64) error {
+	qb := database.PSQL.
+		Delete(database.InvitesTable).
+		Where(sq.Eq{"group_id": groupID, "user_id": userID})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredInvites deletes expired invites of the group, they are never returned anyway.
+func (*Repository) DeleteExpiredInvites(ctx context.Context, q database.Queryable, groupID int64) error {
+	qb := database.PSQL.
+		Delete(database.InvitesTable).
+		Where(sq.Eq{"group_id": groupID}).
+		Where("expires_at <= now()")
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package invites
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type inviteDTO struct {
+	ID        int64
+	GroupID   int64
+	InviterID int64
+	UserID    int64
+	CodeHash  string
+	Role      string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func mapToInvite(d *inviteDTO) *model.GroupInvite {
+	return &model.GroupInvite{
+		ID:        d.ID,
+		GroupID:   d.GroupID,
+		InviterID: d.InviterID,
+		UserID:    d.UserID,
+		CodeHash:  d.CodeHash,
+		Role:      model.GroupRole(d.Role),
+		ExpiresAt: d.ExpiresAt,
+		CreatedAt: d.CreatedAt,
+	}
+}
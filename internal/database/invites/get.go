@@ -0,0 +1,52 @@
+package invites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgx/v4"
+)
+
+func (*Repository) GetInviteByCodeHash(ctx context.Context, q database.Queryable, hash string) (*model.GroupInvite, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"code_hash": hash})
+
+	dto := &inviteDTO{}
+	if err := q.Get(ctx, dto, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNoRecord
+		}
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return mapToInvite(dto), nil
+}
+
+func (*Repository) GetInvites(ctx context.Context, q database.Queryable, filter model.InvitesFilter) ([]*model.GroupInvite, error) {
+	qb := baseQuery.
+		OrderBy("id")
+
+	if len(filter.GroupIDs) != 0 {
+		qb = qb.Where(sq.Eq{"group_id": filter.GroupIDs})
+	}
+
+	if len(filter.UserIDs) != 0 {
+		qb = qb.Where(sq.Eq{"user_id": filter.UserIDs})
+	}
+
+	var dtos []*inviteDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.GroupInvite, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToInvite(d)
+	}
+
+	return res, nil
+}
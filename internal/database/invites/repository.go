@@ -0,0 +1,8 @@
+package invites
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
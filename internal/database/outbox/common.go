@@ -0,0 +1,19 @@
+package outbox
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"id",
+		"kind",
+		"user_id",
+		"group_id",
+		"event_id",
+		"occurrence",
+		"notify_offset",
+		"send_at",
+		"data",
+		"channels",
+		"status",
+	).
+	From(database.OutboxTable)
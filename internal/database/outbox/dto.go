@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type notificationDTO struct {
+	ID           int64
+	Kind         int
+	UserID       int64
+	GroupID      *int64
+	EventID      *int64
+	Occurrence   *time.Time
+	NotifyOffset *int64
+	SendAt       time.Time
+	Data         map[string]string
+	Channels     []string
+	Status       int
+}
+
+func mapToNotification(dto *notificationDTO) *model.ScheduledNotification {
+	res := &model.ScheduledNotification{
+		ID:     dto.ID,
+		Kind:   model.NotificationKind(dto.Kind),
+		UserID: dto.UserID,
+		SendAt: dto.SendAt,
+		Data:   dto.Data,
+		Status: model.NotificationStatus(dto.Status),
+	}
+
+	if dto.GroupID != nil {
+		res.GroupID = *dto.GroupID
+	}
+	if dto.EventID != nil {
+		res.EventID = *dto.EventID
+	}
+
+	if dto.Occurrence != nil {
+		res.Occurrence = *dto.Occurrence
+	}
+	if dto.NotifyOffset != nil {
+		res.Offset = time.Duration(*dto.NotifyOffset)
+	}
+	if dto.Channels != nil {
+		res.Channels = make([]model.Channel, len(dto.Channels))
+		for i, c := range dto.Channels {
+			res.Channels[i] = model.Channel(c)
+		}
+	}
+
+	return res
+}
+
+// mapToChangeData builds the push payload of the change, the number of merged changes is counted in the database.
+func mapToChangeData(c *model.GroupChange) map[string]string {
+	res := map[string]string{
+		"change":   string(c.Type),
+		"changes":  "1",
+		"group_id": strconv.FormatInt(c.GroupID, 10),
+		"actor_id": strconv.FormatInt(c.ActorID, 10),
+	}
+
+	switch c.Type {
+	case model.ChangeTypeGroupRenamed:
+		res["group_name"] = c.Title
+	case model.ChangeTypeMembersAdded, model.ChangeTypeMembersRemoved, model.ChangeTypeRolesChanged:
+		ids := make([]string, len(c.MemberIDs))
+		for i, id := range c.MemberIDs {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		res["member_ids"] = strings.Join(ids, ",")
+	default:
+		res["event_id"] = c.EventID
+		res["event_title"] = c.Title
+	}
+
+	return res
+}
+
+func mapFromChannels(channels []model.Channel) []string {
+	res := make([]string, len(channels))
+	for i, c := range channels {
+		res[i] = string(c)
+	}
+	return res
+}
@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) GetNotification(ctx context.Context, q database.Queryable, id int64) (*model.ScheduledNotification, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"id": id})
+
+	var dtos []*notificationDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	if len(dtos) == 0 {
+		return nil, model.ErrNoRecord
+	}
+
+	return mapToNotification(dtos[0]), nil
+}
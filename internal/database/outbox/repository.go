@@ -0,0 +1,8 @@
+package outbox
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
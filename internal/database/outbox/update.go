@@ -0,0 +1,240 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// insertBatchSize keeps the number of query parameters within the postgres limit.
+const insertBatchSize = 1000
+
+// AddNotifications stores notifications in the outbox, notifications that were already scheduled are skipped,
+// so that every instance can schedule the same interval.
+func (*Repository) AddNotifications(ctx context.Context, q database.Queryable, notifications []*model.ScheduledNotification) error {
+	for i := 0; i < len(notifications); i += insertBatchSize {
+		to := i + insertBatchSize
+		if to > len(notifications) {
+			to = len(notifications)
+		}
+
+		qb := database.PSQL.
+			Insert(database.OutboxTable).
+			Columns(
+				"kind",
+				"user_id",
+				"group_id",
+				"event_id",
+				"occurrence",
+				"notify_offset",
+				"send_at",
+				"data",
+				"channels",
+			).
+			Suffix("on conflict do nothing")
+
+		for _, n := range notifications[i:to] {
+			var groupID, eventID, occurrence, offset, channels interface{}
+			if n.GroupID != 0 {
+				groupID = n.GroupID
+			}
+			if n.EventID != 0 {
+				eventID = n.EventID
+			}
+			if !n.Occurrence.IsZero() {
+				occurrence = n.Occurrence
+				offset = int64(n.Offset)
+			}
+			if n.Channels != nil {
+				channels = mapFromChannels(n.Channels)
+			}
+
+			qb = qb.Values(
+				n.Kind,
+				n.UserID,
+				groupID,
+				eventID,
+				occurrence,
+				offset,
+				n.SendAt,
+				n.Data,
+				channels,
+			)
+		}
+
+		if _, err := q.Exec(ctx, qb); err != nil {
+			return fmt.Errorf("SQL request: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AddChangeNotifications schedules the change for members of the group, who want to be notified.
+// Changes made before the pending notification is sent are merged into it and counted, so that
+// quick edits result in a single push. Every change postpones the push to its sendAt, but no longer
+// than maxDelay after the first merged change, so that the push isn't postponed forever by ongoing edits.
+func (*Repository) AddChangeNotifications(ctx context.Context, q database.Queryable, change *model.GroupChange, sendAt time.Time, maxDelay time.Duration) error {
+	// subquery keeps question placeholders, they are numbered together with the suffix by the outer query
+	recipients := sq.
+		Select("ug.user_id", "ug.group_id").
+		Column("?::smallint", model.NotificationKindChange).
+		Column("?::timestamptz", sendAt).
+		Column("?::jsonb", mapToChangeData(change)).
+		From(database.UserGroupTable + " ug").
+		Join(database.UsersTable + " u on u.id = ug.user_id").
+		Where(sq.Eq{"ug.group_id": change.GroupID}).
+		Where(sq.NotEq{"ug.user_id": change.ActorID}).
+		Where("ug.notify and u.notify")
+
+	qb := database.PSQL.
+		Insert(database.OutboxTable).
+		Columns(
+			"user_id",
+			"group_id",
+			"kind",
+			"send_at",
+			"data",
+		).
+		Select(recipients).
+		Suffix(`on conflict (user_id, group_id) where kind = 1 and status = 0 do update set
+			send_at = least(
+				greatest(notification_outbox.send_at, excluded.send_at),
+				notification_outbox.created_at + make_interval(secs => ?)
+			),
+			data = excluded.data || jsonb_build_object('changes', ((notification_outbox.data ->> 'changes')::int + 1)::text)`,
+			maxDelay.Seconds(),
+		)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimNotifications marks due reminders as claimed and returns them. Rows locked by other
+// instances are skipped, so that each reminder is claimed only once.
+func (*Repository) ClaimNotifications(ctx context.Context, q database.Queryable, filter model.ClaimFilter) ([]*model.ScheduledNotification, error) {
+	// subquery keeps question placeholders, they are numbered by the outer query
+	due := sq.
+		Select("id").
+		From(database.OutboxTable).
+		Where(sq.Lt{"send_at": filter.SendBefore}).
+		Where(sq.GtOrEq{"send_at": filter.SendAfter}).
+		Where(sq.Or{
+			sq.Eq{"status": model.NotificationStatusPending},
+			sq.And{
+				sq.Eq{"status": model.NotificationStatusClaimed},
+				sq.Lt{"claimed_at": filter.ClaimedBefore},
+			},
+		}).
+		OrderBy("send_at").
+		Limit(filter.Limit).
+		Suffix("for update skip locked")
+
+	qb := database.PSQL.
+		Update(database.OutboxTable).
+		Set("status", model.NotificationStatusClaimed).
+		Set("claimed_at", sq.Expr("now()")).
+		Where(sq.Expr("id in (?)", due)).
+		Suffix("returning id, kind, user_id, group_id, event_id, occurrence, notify_offset, send_at, data, channels, status")
+
+	var dtos []*notificationDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.ScheduledNotification, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToNotification(d)
+	}
+
+	return res, nil
+}
+
+// MarkNotifications sets the final status of processed reminders.
+func (*Repository) MarkNotifications(ctx context.Context, q database.Queryable, ids []int64, status model.NotificationStatus) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	qb := database.PSQL.
+		Update(database.OutboxTable).
+		Set("status", status).
+		Set("sent_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": ids})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeferNotifications returns claimed notifications to the outbox to be sent at the given time.
+// A change notification is left claimed if a newer one for the group is already pending, it is
+// deferred again after the claim timeout.
+func (*Repository) DeferNotifications(ctx context.Context, q database.Queryable, ids []int64, sendAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	pendingChange := sq.
+		Select("1").
+		From(database.OutboxTable + " p").
+		Where("p.user_id = " + database.OutboxTable + ".user_id").
+		Where("p.group_id = " + database.OutboxTable + ".group_id").
+		Where(sq.Eq{"p.kind": model.NotificationKindChange}).
+		Where(sq.Eq{"p.status": model.NotificationStatusPending})
+
+	qb := database.PSQL.
+		Update(database.OutboxTable).
+		Set("status", model.NotificationStatusPending).
+		Set("send_at", sendAt).
+		Set("claimed_at", nil).
+		Where(sq.Eq{"id": ids}).
+		Where(sq.Or{
+			sq.NotEq{"kind": model.NotificationKindChange},
+			sq.Expr("not exists (?)", pendingChange),
+		})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireNotifications marks reminders that were not sent before the given time as expired.
+func (*Repository) ExpireNotifications(ctx context.Context, q database.Queryable, before time.Time) error {
+	qb := database.PSQL.
+		Update(database.OutboxTable).
+		Set("status", model.NotificationStatusExpired).
+		Where(sq.Eq{"status": []model.NotificationStatus{model.NotificationStatusPending, model.NotificationStatusClaimed}}).
+		Where(sq.Lt{"send_at": before})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteNotifications removes reminders scheduled before the given time. It must be earlier than
+// the start of any interval that can be scheduled again, otherwise reminders are sent twice.
+func (*Repository) DeleteNotifications(ctx context.Context, q database.Queryable, before time.Time) error {
+	qb := database.PSQL.
+		Delete(database.OutboxTable).
+		Where(sq.Lt{"send_at": before})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package passwords
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"id",
+		"user_id",
+		"name",
+		"hash",
+		"created_at",
+		"last_used_at",
+	).
+	From(database.PasswordsTable)
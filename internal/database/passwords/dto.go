@@ -0,0 +1,27 @@
+package passwords
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type passwordDTO struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Hash       string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func mapToPassword(d *passwordDTO) *model.AppPassword {
+	return &model.AppPassword{
+		ID:         d.ID,
+		UserID:     d.UserID,
+		Name:       d.Name,
+		Hash:       d.Hash,
+		CreatedAt:  d.CreatedAt,
+		LastUsedAt: d.LastUsedAt,
+	}
+}
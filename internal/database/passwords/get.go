@@ -0,0 +1,45 @@
+package passwords
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgx/v4"
+)
+
+func (*Repository) GetAppPasswordByHash(ctx context.Context, q database.Queryable, hash string) (*model.AppPassword, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"hash": hash})
+
+	dto := &passwordDTO{}
+	if err := q.Get(ctx, dto, qb); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.ErrNoRecord
+		}
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return mapToPassword(dto), nil
+}
+
+func (*Repository) GetUserAppPasswords(ctx context.Context, q database.Queryable, userID int64) ([]*model.AppPassword, error) {
+	qb := baseQuery.
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("id")
+
+	var dtos []*passwordDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.AppPassword, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToPassword(d)
+	}
+
+	return res, nil
+}
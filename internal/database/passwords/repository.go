@@ -0,0 +1,8 @@
+package passwords
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
@@ -0,0 +1,63 @@
+package passwords
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/jackc/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+func (*Repository) CreateAppPassword(ctx context.Context, q database.Queryable, password *model.AppPassword) (*model.AppPassword, error) {
+	qb := database.PSQL.
+		Insert(database.PasswordsTable).
+		Columns("user_id", "name", "hash").
+		Values(password.UserID, password.Name, password.Hash).
+		Suffix("returning id, user_id, name, hash, created_at, last_used_at")
+
+	dto := &passwordDTO{}
+	if err := q.Get(ctx, dto, qb); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return nil, model.ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	return mapToPassword(dto), nil
+}
+
+func (*Repository) TouchAppPassword(ctx context.Context, q database.Queryable, id int64) error {
+	qb := database.PSQL.
+		Update(database.PasswordsTable).
+		Set("last_used_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteAppPassword(ctx context.Context, q database.Queryable, userID int64, id int64) error {
+	qb := database.PSQL.
+		Delete(database.PasswordsTable).
+		Where(sq.Eq{"id": id, "user_id": userID})
+
+	res, err := q.Exec(ctx, qb)
+	if err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return model.ErrNoRecord
+	}
+
+	return nil
+}
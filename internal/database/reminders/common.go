@@ -0,0 +1,14 @@
+package reminders
+
+import "github.com/SergeyKozhin/shared-planner-backend/internal/database"
+
+var baseQuery = database.PSQL.
+	Select(
+		"user_id",
+		"event_id",
+		"custom",
+		"notifications",
+		"notify_at",
+		"muted",
+	).
+	From(database.RemindersTable)
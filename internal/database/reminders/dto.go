@@ -0,0 +1,60 @@
+package reminders
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+type remindersDTO struct {
+	UserID        int64
+	EventID       int64
+	Custom        bool
+	Notifications []int64
+	NotifyAt      []time.Time
+	Muted         bool
+}
+
+func mapToReminders(dto *remindersDTO) *model.Reminders {
+	notifications := make([]time.Duration, len(dto.Notifications))
+	for i, n := range dto.Notifications {
+		notifications[i] = time.Duration(n)
+	}
+
+	return &model.Reminders{
+		UserID:        dto.UserID,
+		EventID:       dto.EventID,
+		Custom:        dto.Custom,
+		Notifications: notifications,
+		NotifyAt:      dto.NotifyAt,
+		Muted:         dto.Muted,
+	}
+}
+
+type ackDTO struct {
+	UserID     int64
+	EventID    int64
+	Occurrence time.Time
+}
+
+func mapToAck(dto *ackDTO) *model.ReminderAck {
+	return &model.ReminderAck{
+		UserID:     dto.UserID,
+		EventID:    dto.EventID,
+		Occurrence: dto.Occurrence,
+	}
+}
+
+type scheduleDTO struct {
+	EventID      int64
+	NotifyOffset int64
+	NextAt       time.Time
+}
+
+func mapToSchedule(dto *scheduleDTO) *model.ReminderSchedule {
+	return &model.ReminderSchedule{
+		EventID: dto.EventID,
+		Offset:  time.Duration(dto.NotifyOffset),
+		NextAt:  dto.NextAt,
+	}
+}
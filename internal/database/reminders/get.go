@@ -0,0 +1,97 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) GetReminders(ctx context.Context, q database.Queryable, filter model.RemindersFilter) ([]*model.Reminders, error) {
+	qb := baseQuery.
+		OrderBy("event_id", "user_id")
+
+	if len(filter.UserIDs) != 0 {
+		qb = qb.Where(sq.Eq{"user_id": filter.UserIDs})
+	}
+
+	if len(filter.EventIDs) != 0 {
+		qb = qb.Where(sq.Eq{"event_id": filter.EventIDs})
+	}
+
+	if !filter.NotifyFrom.IsZero() && !filter.NotifyTo.IsZero() {
+		qb = qb.
+			Where(sq.Eq{"custom": true}).
+			Where("exists (select from unnest(notify_at) t where t >= ? and t < ?)", filter.NotifyFrom, filter.NotifyTo)
+	}
+
+	var dtos []*remindersDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.Reminders, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToReminders(d)
+	}
+
+	return res, nil
+}
+
+func (*Repository) GetReminderAcks(ctx context.Context, q database.Queryable, filter model.ReminderAcksFilter) ([]*model.ReminderAck, error) {
+	qb := database.PSQL.
+		Select(
+			"user_id",
+			"event_id",
+			"occurrence",
+		).
+		From(database.ReminderAcksTable)
+
+	if len(filter.UserIDs) != 0 {
+		qb = qb.Where(sq.Eq{"user_id": filter.UserIDs})
+	}
+
+	if len(filter.EventIDs) != 0 {
+		qb = qb.Where(sq.Eq{"event_id": filter.EventIDs})
+	}
+
+	var dtos []*ackDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.ReminderAck, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToAck(d)
+	}
+
+	return res, nil
+}
+
+// GetDueSchedules returns schedules of reminders that are sent before the given time.
+func (*Repository) GetDueSchedules(ctx context.Context, q database.Queryable, before time.Time) ([]*model.ReminderSchedule, error) {
+	qb := database.PSQL.
+		Select(
+			"event_id",
+			"notify_offset",
+			"next_at",
+		).
+		From(database.ScheduleTable).
+		Where(sq.Lt{"next_at": before}).
+		OrderBy("next_at")
+
+	var dtos []*scheduleDTO
+	if err := q.Select(ctx, &dtos, qb); err != nil {
+		return nil, fmt.Errorf("SQL request: %w", err)
+	}
+
+	res := make([]*model.ReminderSchedule, len(dtos))
+	for i, d := range dtos {
+		res[i] = mapToSchedule(d)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,8 @@
+package reminders
+
+type Repository struct {
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
@@ -0,0 +1,180 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+func (*Repository) UpsertReminders(ctx context.Context, q database.Queryable, reminders *model.Reminders) error {
+	notifications := make([]int64, len(reminders.Notifications))
+	for i, n := range reminders.Notifications {
+		notifications[i] = int64(n)
+	}
+
+	qb := database.PSQL.
+		Insert(database.RemindersTable).
+		Columns(
+			"user_id",
+			"event_id",
+			"custom",
+			"notifications",
+			"notify_at",
+			"muted",
+		).
+		Values(
+			reminders.UserID,
+			reminders.EventID,
+			reminders.Custom,
+			notifications,
+			reminders.NotifyAt,
+			reminders.Muted,
+		).
+		Suffix(`on conflict (user_id, event_id) do update set
+			custom = excluded.custom,
+			notifications = excluded.notifications,
+			notify_at = excluded.notify_at,
+			muted = excluded.muted,
+			updated_at = now()`)
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) DeleteReminders(ctx context.Context, q database.Queryable, userID int64, eventID int64) error {
+	qb := database.PSQL.
+		Delete(database.RemindersTable).
+		Where(sq.Eq{"user_id": userID, "event_id": eventID})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// SplitReminders gives members the same personal reminders for the series split from the event at ts.
+// Absolute reminders starting from ts are moved to the new series, so that they are not sent twice.
+func (*Repository) SplitReminders(ctx context.Context, q database.Queryable, fromEventID int64, toEventID int64, ts time.Time) error {
+	copyQb := database.PSQL.
+		Insert(database.RemindersTable).
+		Columns(
+			"user_id",
+			"event_id",
+			"custom",
+			"notifications",
+			"notify_at",
+			"muted",
+		).
+		Select(database.PSQL.
+			Select().
+			Column("user_id").
+			Column(sq.Expr("?::bigint", toEventID)).
+			Columns(
+				"custom",
+				"notifications",
+			).
+			Column(sq.Expr("array(select t from unnest(notify_at) t where t >= ?)", ts)).
+			Column("muted").
+			From(database.RemindersTable).
+			Where(sq.Eq{"event_id": fromEventID})).
+		Suffix("on conflict (user_id, event_id) do nothing")
+
+	if _, err := q.Exec(ctx, copyQb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	qb := database.PSQL.
+		Update(database.RemindersTable).
+		Set("notify_at", sq.Expr("array(select t from unnest(notify_at) t where t < ?)", ts)).
+		Where(sq.Eq{"event_id": fromEventID})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// AddReminderAck acknowledges the occurrence, acknowledging it again does nothing.
+func (*Repository) AddReminderAck(ctx context.Context, q database.Queryable, ack *model.ReminderAck) error {
+	qb := database.PSQL.
+		Insert(database.ReminderAcksTable).
+		Columns(
+			"user_id",
+			"event_id",
+			"occurrence",
+		).
+		Values(
+			ack.UserID,
+			ack.EventID,
+			ack.Occurrence,
+		).
+		Suffix("on conflict do nothing")
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReminderAcks removes acknowledgements of occurrences before the given time.
+func (*Repository) DeleteReminderAcks(ctx context.Context, q database.Queryable, before time.Time) error {
+	qb := database.PSQL.
+		Delete(database.ReminderAcksTable).
+		Where(sq.Lt{"occurrence": before})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// SetSchedules replaces the schedule of the series, offsets missing in schedules are removed.
+func (*Repository) SetSchedules(ctx context.Context, q database.Queryable, eventID int64, schedules []*model.ReminderSchedule) error {
+	offsets := make([]int64, len(schedules))
+	for i, s := range schedules {
+		offsets[i] = int64(s.Offset)
+	}
+
+	deleteQuery := database.PSQL.
+		Delete(database.ScheduleTable).
+		Where(sq.Eq{"event_id": eventID}).
+		Where(sq.NotEq{"notify_offset": offsets})
+
+	if _, err := q.Exec(ctx, deleteQuery); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	qb := database.PSQL.
+		Insert(database.ScheduleTable).
+		Columns(
+			"event_id",
+			"notify_offset",
+			"next_at",
+		).
+		Suffix("on conflict (event_id, notify_offset) do update set next_at = excluded.next_at")
+
+	for _, s := range schedules {
+		qb = qb.Values(eventID, int64(s.Offset), s.NextAt)
+	}
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
@@ -1,8 +1,21 @@
 package database
 
 const (
-	UsersTable     = "users"
-	GroupsTable    = "groups"
-	UserGroupTable = "user_group"
-	EventsTable    = "events"
+	UsersTable        = "users"
+	GroupsTable       = "groups"
+	UserGroupTable    = "user_group"
+	InvitesTable      = "group_invites"
+	EventsTable       = "events"
+	OverridesTable    = "event_overrides"
+	ChangesTable      = "changes"
+	HorizonTable      = "changes_horizon"
+	FeedsTable        = "feed_tokens"
+	PasswordsTable    = "app_passwords"
+	RemindersTable    = "event_reminders"
+	ReminderAcksTable = "reminder_acks"
+	ScheduleTable     = "reminder_schedule"
+	OutboxTable       = "notification_outbox"
+	DevicesTable      = "devices"
+	InboxTable        = "inbox"
+	DigestsTable      = "digest_settings"
 )
@@ -11,7 +11,14 @@ var baseQuery = database.PSQL.
 		"email",
 		"phone_number",
 		"photo",
-		"push_token",
 		"notify",
+		"time_zone",
+		"channels",
+		"webhook_url",
+		"quiet_enabled",
+		"quiet_start",
+		"quiet_end",
+		"quiet_policy",
+		"snooze_until",
 	).
 	From(database.UsersTable)
@@ -1,25 +1,53 @@
 package user
 
 import (
+	"time"
+
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 )
 
 type userDTO struct {
-	ID          int64
-	FullName    string
-	Email       string
-	PhoneNumber string
-	Photo       string
-	PushToken   string
-	Notify      bool
-	GroupsIDs   []int64
+	ID           int64
+	FullName     string
+	Email        string
+	PhoneNumber  string
+	Photo        string
+	Notify       bool
+	TimeZone     string
+	Channels     []string
+	WebhookURL   string
+	QuietEnabled bool
+	QuietStart   int
+	QuietEnd     int
+	QuietPolicy  string
+	SnoozeUntil  *time.Time
+	GroupsIDs    []int64
 }
 
 func mapToUser(dto *userDTO) *model.User {
+	channels := make([]model.Channel, len(dto.Channels))
+	for i, c := range dto.Channels {
+		channels[i] = model.Channel(c)
+	}
+
+	var snoozeUntil time.Time
+	if dto.SnoozeUntil != nil {
+		snoozeUntil = *dto.SnoozeUntil
+	}
+
 	return &model.User{
-		ID:        dto.ID,
-		PushToken: dto.PushToken,
-		Notify:    dto.Notify,
+		ID:         dto.ID,
+		Notify:     dto.Notify,
+		TimeZone:   dto.TimeZone,
+		Channels:   channels,
+		WebhookURL: dto.WebhookURL,
+		Quiet: model.QuietHours{
+			Enabled:     dto.QuietEnabled,
+			Start:       dto.QuietStart,
+			End:         dto.QuietEnd,
+			Policy:      model.QuietPolicy(dto.QuietPolicy),
+			SnoozeUntil: snoozeUntil,
+		},
 		UserCreate: model.UserCreate{
 			FullName:    dto.FullName,
 			Email:       dto.Email,
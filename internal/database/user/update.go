@@ -3,15 +3,17 @@ package user
 import (
 	"context"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
 )
 
-func (*Repository) UpdateUserPushToken(ctx context.Context, q database.Queryable, id int64, token string) error {
+func (*Repository) UpdateNotify(ctx context.Context, q database.Queryable, id int64, notify bool) error {
 	qb := database.PSQL.
 		Update(database.UsersTable).
-		Set("push_token", token).
+		Set("notify", notify).
 		Where(sq.Eq{"id": id})
 
 	if _, err := q.Exec(ctx, qb); err != nil {
@@ -21,10 +23,65 @@ func (*Repository) UpdateUserPushToken(ctx context.Context, q database.Queryable
 	return nil
 }
 
-func (*Repository) UpdateNotify(ctx context.Context, q database.Queryable, id int64, notify bool) error {
+func (*Repository) UpdateTimeZone(ctx context.Context, q database.Queryable, id int64, timeZone string) error {
 	qb := database.PSQL.
 		Update(database.UsersTable).
-		Set("notify", notify).
+		Set("time_zone", timeZone).
+		Where(sq.Eq{"id": id})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+func (*Repository) UpdateChannels(ctx context.Context, q database.Queryable, id int64, channels []model.Channel, webhookURL string) error {
+	values := make([]string, len(channels))
+	for i, c := range channels {
+		values[i] = string(c)
+	}
+
+	qb := database.PSQL.
+		Update(database.UsersTable).
+		Set("channels", values).
+		Set("webhook_url", webhookURL).
+		Where(sq.Eq{"id": id})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateQuietHours sets quiet hours of the user, the snooze is left as it is.
+func (*Repository) UpdateQuietHours(ctx context.Context, q database.Queryable, id int64, quiet *model.QuietHours) error {
+	qb := database.PSQL.
+		Update(database.UsersTable).
+		Set("quiet_enabled", quiet.Enabled).
+		Set("quiet_start", quiet.Start).
+		Set("quiet_end", quiet.End).
+		Set("quiet_policy", quiet.Policy).
+		Where(sq.Eq{"id": id})
+
+	if _, err := q.Exec(ctx, qb); err != nil {
+		return fmt.Errorf("SQL request: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSnoozeUntil snoozes notifications of the user, zero time cancels the snooze.
+func (*Repository) UpdateSnoozeUntil(ctx context.Context, q database.Queryable, id int64, until time.Time) error {
+	var value interface{}
+	if !until.IsZero() {
+		value = until
+	}
+
+	qb := database.PSQL.
+		Update(database.UsersTable).
+		Set("snooze_until", value).
 		Where(sq.Eq{"id": id})
 
 	if _, err := q.Exec(ctx, qb); err != nil {
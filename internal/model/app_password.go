@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// AppPassword authenticates DAV clients of the user. Only the hash of the password is stored.
+type AppPassword struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Hash       string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
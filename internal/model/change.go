@@ -0,0 +1,24 @@
+package model
+
+type EntityType int
+
+const (
+	EntityTypeEvent EntityType = iota
+	EntityTypeGroup
+	EntityTypeMembership
+)
+
+// Change is an entry of the change log. Deleted entities are not stored separately,
+// entity that is changed but can't be found anymore is considered deleted.
+type Change struct {
+	EntityType EntityType
+	EntityID   int64
+	GroupID    int64
+	UserID     int64
+}
+
+type ChangesFilter struct {
+	SyncPoint int64
+	GroupIDs  []int64
+	UserID    int64
+}
@@ -0,0 +1,11 @@
+package model
+
+// Channel is a way notifications are delivered to the user.
+type Channel string
+
+const (
+	ChannelPush    Channel = "push"
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+	ChannelSMS     Channel = "sms"
+)
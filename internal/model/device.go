@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type Platform string
+
+const (
+	PlatformAndroid Platform = "android"
+	PlatformIOS     Platform = "ios"
+	PlatformWeb     Platform = "web"
+)
+
+// Device receives push notifications while its refresh token session is alive.
+// Devices registered by legacy clients have no session, they are removed by any logout of the user.
+type Device struct {
+	ID          int64
+	UserID      int64
+	SessionHash string
+	PushToken   string
+	Platform    Platform
+	AppVersion  string
+	CreatedAt   time.Time
+	LastSeenAt  time.Time
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+type DigestType string
+
+const (
+	DigestTypeDaily  DigestType = "daily"
+	DigestTypeWeekly DigestType = "weekly"
+)
+
+// DigestSettings times are minutes after the local midnight in the time zone of the user.
+// Daily digest covers the day it is sent, weekly one covers seven days after the day it is sent.
+type DigestSettings struct {
+	UserID     int64
+	Daily      bool
+	DailyTime  int
+	Weekly     bool
+	WeeklyDay  time.Weekday
+	WeeklyTime int
+	// GroupIDs limit the digest to the groups, empty GroupIDs means all groups of the user.
+	GroupIDs []int64
+	Email    bool
+}
+
+type DigestSettingsFilter struct {
+	UserIDs []int64
+	// Enabled selects settings with at least one digest turned on.
+	Enabled bool
+}
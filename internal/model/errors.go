@@ -4,3 +4,4 @@ import "errors"
 
 var ErrNoRecord = errors.New("no record")
 var ErrAlreadyExists = errors.New("entity already exists")
+var ErrEditConflict = errors.New("edit conflict")
@@ -2,6 +2,23 @@ package model
 
 import "time"
 
+// NotificationTypes are reminder offsets of old clients, which refer to them by index
+// in the notifications field and in the notification_type push data.
+var NotificationTypes = []time.Duration{
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	24 * time.Hour,
+}
+
+// AnyVersion is passed instead of the version seen by the client to overwrite the event
+// without the check, it is used only by CalDAV requests without If-Match.
+const AnyVersion int64 = -1
+
+// EventCreate holds reminders of two kinds: Notifications are offsets before the start of every
+// occurrence, while NotifyAt are absolute times that belong to the whole series.
 type EventCreate struct {
 	GroupID       int64
 	EventType     EventType
@@ -10,9 +27,15 @@ type EventCreate struct {
 	AllDay        bool
 	From          time.Time
 	To            time.Time
+	TimeZone      string
 	RepeatType    RepeatType
+	Recurrence    *Recurrence
+	RepeatEnd     *RepeatEnd
 	Notifications []time.Duration
+	NotifyAt      []time.Time
 	Attachments   []*Attachment
+	// UID is the iCalendar UID of the imported event, it is empty for events created in the app.
+	UID string
 }
 
 type Attachment struct {
@@ -21,13 +44,35 @@ type Attachment struct {
 }
 
 type Event struct {
-	ID         string
-	RepeatRule string
-	Exceptions map[int64]struct{}
-	Until      *time.Time
+	ID           string
+	RepeatRule   string
+	Exceptions   map[int64]struct{}
+	Until        *time.Time
+	RecurrenceID *time.Time
+	CreatorID    int64
+	UpdatedBy    int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Version      int64
 	EventCreate
 }
 
+// EventOverride replaces a single occurrence of a series, that started at OriginalStart.
+type EventOverride struct {
+	EventID       int64
+	OriginalStart time.Time
+	EventType     EventType
+	Title         string
+	Description   string
+	AllDay        bool
+	From          time.Time
+	To            time.Time
+	Notifications []time.Duration
+	Attachments   []*Attachment
+	UpdatedBy     int64
+	UpdatedAt     time.Time
+}
+
 type EventUpdate struct {
 	GroupID       int64
 	EventType     EventType
@@ -36,7 +81,10 @@ type EventUpdate struct {
 	AllDay        bool
 	From          time.Time
 	To            time.Time
+	TimeZone      string
+	RepeatEnd     *RepeatEnd
 	Notifications []time.Duration
+	NotifyAt      []time.Time
 }
 
 type EventType int
@@ -55,10 +103,62 @@ const (
 	RepeatTypeEveryWeek
 	RepeatTypeEveryMonth
 	RepeatTypeEveryYear
+	RepeatTypeCustom
 )
 
+type Frequency int
+
+const (
+	FrequencyDaily Frequency = iota
+	FrequencyWeekly
+	FrequencyMonthly
+	FrequencyYearly
+)
+
+// Recurrence is a structured subset of RFC 5545 RRULE.
+type Recurrence struct {
+	Frequency  Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	BySetPos   []int
+	Count      int
+	Until      *time.Time
+}
+
+// RepeatEnd limits a series either by the last occurrence date or by the number of occurrences.
+// Empty RepeatEnd means the series repeats forever.
+type RepeatEnd struct {
+	Until *time.Time
+	Count int
+}
+
+// WeekdayNum is a BYDAY entry, N is the optional ordinal (e.g. -1 for the last Friday).
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Series is a stored event with all of its overrides, occurrences are not expanded.
+type Series struct {
+	Event     *Event
+	Overrides []*EventOverride
+}
+
 type EventsFilter struct {
+	From       time.Time
+	To         time.Time
+	IDs        []int64
+	GroupIDs   []int64
+	CreatorIDs []int64
+	UIDs       []string
+	// NotifyFrom and NotifyTo select events with absolute reminders in [NotifyFrom, NotifyTo).
+	NotifyFrom time.Time
+	NotifyTo   time.Time
+}
+
+type OverridesFilter struct {
+	EventIDs []int64
 	From     time.Time
 	To       time.Time
-	GroupIDs []int64
 }
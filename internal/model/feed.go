@@ -0,0 +1,8 @@
+package model
+
+// Feed is a secret link to the iCalendar feed of the user. Zero GroupID means that all user groups are included.
+type Feed struct {
+	Token   string
+	UserID  int64
+	GroupID int64
+}
@@ -4,6 +4,20 @@ import (
 	"github.com/gerow/go-color"
 )
 
+// GroupRole is the role of the member in the group, it tells what the member is allowed to do.
+type GroupRole string
+
+const (
+	// GroupRoleOwner has all rights of the admin and can grant or revoke the admin role.
+	GroupRoleOwner GroupRole = "owner"
+	// GroupRoleAdmin manages the group, its members and their roles.
+	GroupRoleAdmin GroupRole = "admin"
+	// GroupRoleEditor creates, edits and deletes events of the group.
+	GroupRoleEditor GroupRole = "editor"
+	// GroupRoleViewer can only read events of the group.
+	GroupRoleViewer GroupRole = "viewer"
+)
+
 type GroupCreate struct {
 	Name      string
 	CreatorID int64
@@ -12,6 +26,9 @@ type GroupCreate struct {
 type Group struct {
 	ID       int64
 	UsersIDs []int64
+	// Roles of the members by user id.
+	Roles   map[int64]GroupRole
+	Version int64
 	GroupCreate
 }
 
@@ -20,6 +37,8 @@ type GroupSettings struct {
 	GroupID int64
 	Color   color.RGB
 	Notify  bool
+	// Role is set only when the user is added to the group.
+	Role GroupRole
 }
 
 type UserGroupSettingsFilter struct {
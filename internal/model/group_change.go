@@ -0,0 +1,25 @@
+package model
+
+type ChangeType string
+
+const (
+	ChangeTypeEventCreated     ChangeType = "event_created"
+	ChangeTypeEventRescheduled ChangeType = "event_rescheduled"
+	ChangeTypeEventCancelled   ChangeType = "event_cancelled"
+	ChangeTypeInstanceDeleted  ChangeType = "instance_deleted"
+	ChangeTypeMembersAdded     ChangeType = "members_added"
+	ChangeTypeMembersRemoved   ChangeType = "members_removed"
+	ChangeTypeRolesChanged     ChangeType = "roles_changed"
+	ChangeTypeGroupRenamed     ChangeType = "group_renamed"
+)
+
+// GroupChange is pushed to members of the group except the one who made it.
+// EventID has the instance format, Title is the title of the event or the new name of the group.
+type GroupChange struct {
+	GroupID   int64
+	ActorID   int64
+	Type      ChangeType
+	EventID   string
+	Title     string
+	MemberIDs []int64
+}
@@ -0,0 +1,27 @@
+package model
+
+// ImportedEvent is a series read from an iCalendar file, it is created in the group
+// together with its exceptions and overrides.
+type ImportedEvent struct {
+	EventCreate
+	Exceptions map[int64]struct{}
+	Overrides  []*EventOverride
+	// Warnings describe parts of the event that could not be imported.
+	Warnings []string
+}
+
+type ImportStatus int
+
+const (
+	ImportStatusImported ImportStatus = iota
+	ImportStatusSkipped
+	ImportStatusUnsupported
+)
+
+// ImportItem is a line of the import report.
+type ImportItem struct {
+	UID    string
+	Title  string
+	Status ImportStatus
+	Reason string
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// InboxItem keeps the sent notification for the user. EventID has the instance format,
+// it is empty for notifications that are not about an event.
+type InboxItem struct {
+	ID             int64
+	NotificationID int64
+	UserID         int64
+	Kind           NotificationKind
+	GroupID        int64
+	EventID        string
+	Data           map[string]string
+	ReadAt         *time.Time
+	CreatedAt      time.Time
+}
+
+// InboxFilter selects items of the user from the newest one, BeforeID is the cursor of the page.
+type InboxFilter struct {
+	UserID   int64
+	BeforeID int64
+	Limit    uint64
+}
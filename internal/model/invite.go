@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// GroupInvite is a pending invitation to the group. Personal invites are sent to UserID and
+// accepted or declined by the user, invite links have zero UserID and are joined by the code,
+// only the hash of which is stored.
+type GroupInvite struct {
+	ID        int64
+	GroupID   int64
+	InviterID int64
+	UserID    int64
+	CodeHash  string
+	Role      GroupRole
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// InvitesFilter returns only invites that have not expired yet.
+type InvitesFilter struct {
+	GroupIDs []int64
+	UserIDs  []int64
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+type NotificationStatus int
+
+const (
+	NotificationStatusPending NotificationStatus = iota
+	NotificationStatusClaimed
+	NotificationStatusSent
+	NotificationStatusExpired
+	NotificationStatusFailed
+	// NotificationStatusCancelled is set to reminders of acknowledged occurrences.
+	NotificationStatusCancelled
+	// NotificationStatusDropped is set to notifications that were not sent, because the user turned them off,
+	// has no address for the chosen channels or is in quiet hours with the drop policy.
+	NotificationStatusDropped
+)
+
+type NotificationKind int
+
+const (
+	NotificationKindReminder NotificationKind = iota
+	NotificationKindChange
+	NotificationKindDigest
+)
+
+// ScheduledNotification is a message stored in the outbox. Reminders before the occurrence are keyed
+// by its start and offset, while reminders at the absolute time have zero Occurrence.
+// Change notifications have zero EventID, they are merged per group while pending.
+// Channels override channels chosen by the user, if they are set.
+type ScheduledNotification struct {
+	ID         int64
+	Kind       NotificationKind
+	UserID     int64
+	GroupID    int64
+	EventID    int64
+	Occurrence time.Time
+	Offset     time.Duration
+	SendAt     time.Time
+	Data       map[string]string
+	Channels   []Channel
+	Status     NotificationStatus
+}
+
+type ClaimFilter struct {
+	// SendBefore is the end of the interval of due reminders, reminders sent before SendAfter are expired.
+	SendBefore time.Time
+	SendAfter  time.Time
+	// ClaimedBefore allows to claim again reminders that were not sent by the crashed instance.
+	ClaimedBefore time.Time
+	Limit         uint64
+}
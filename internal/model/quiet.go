@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// QuietPolicy tells what happens to notifications due in quiet hours or while snoozed.
+type QuietPolicy string
+
+const (
+	// QuietPolicyDefer sends notifications when the quiet period ends.
+	QuietPolicyDefer QuietPolicy = "defer"
+	// QuietPolicyDrop doesn't deliver notifications, they are only kept in the inbox.
+	QuietPolicyDrop QuietPolicy = "drop"
+)
+
+// QuietHours times are minutes after the local midnight in the time zone of the user,
+// Start after End means the window spans the midnight.
+type QuietHours struct {
+	Enabled bool
+	Start   int
+	End     int
+	Policy  QuietPolicy
+	// SnoozeUntil silences all notifications until the time, zero if not snoozed.
+	SnoozeUntil time.Time
+}
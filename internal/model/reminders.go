@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Reminders are personal reminder settings of the member for the series.
+type Reminders struct {
+	UserID  int64
+	EventID int64
+	// Custom reports whether Notifications and NotifyAt replace the event defaults.
+	Custom        bool
+	Notifications []time.Duration
+	NotifyAt      []time.Time
+	Muted         bool
+}
+
+type RemindersFilter struct {
+	UserIDs  []int64
+	EventIDs []int64
+	// NotifyFrom and NotifyTo select custom reminders with absolute times in [NotifyFrom, NotifyTo).
+	NotifyFrom time.Time
+	NotifyTo   time.Time
+}
+
+// ReminderAck stops the remaining reminders of the member for the occurrence. Reminders at
+// the absolute time are acknowledged by that time instead of the occurrence.
+type ReminderAck struct {
+	UserID     int64
+	EventID    int64
+	Occurrence time.Time
+}
+
+type ReminderAcksFilter struct {
+	UserIDs  []int64
+	EventIDs []int64
+}
+
+// ReminderSchedule is the next time the reminder with the offset is sent for any occurrence
+// of the series, so that only the series with due reminders are expanded.
+type ReminderSchedule struct {
+	EventID int64
+	Offset  time.Duration
+	NextAt  time.Time
+}
@@ -8,9 +8,12 @@ type UserCreate struct {
 }
 
 type User struct {
-	ID        int64
-	PushToken string
-	Notify    bool
+	ID         int64
+	Notify     bool
+	TimeZone   string
+	Channels   []Channel
+	WebhookURL string
+	Quiet      QuietHours
 	UserCreate
 }
 
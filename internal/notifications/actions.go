@@ -0,0 +1,156 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// SnoozeReminder schedules the reminder again after the given duration and returns when it is sent.
+// Reminders that are not of the user are reported as not found.
+func (s *Sender) SnoozeReminder(ctx context.Context, userID int64, id int64, d time.Duration) (time.Time, error) {
+	n, err := s.getUserReminder(ctx, userID, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	sendAt := time.Now().Add(d).Truncate(time.Second)
+
+	data := make(map[string]string, len(n.Data))
+	for k, v := range n.Data {
+		data[k] = v
+	}
+	delete(data, "reminder_id")
+
+	followUp := &model.ScheduledNotification{
+		Kind:       model.NotificationKindReminder,
+		UserID:     n.UserID,
+		GroupID:    n.GroupID,
+		EventID:    n.EventID,
+		Occurrence: n.Occurrence,
+		SendAt:     sendAt,
+		Data:       data,
+		Channels:   n.Channels,
+	}
+
+	// follow-up of the occurrence is keyed by its own offset, it can be after the start of the event
+	if !n.Occurrence.IsZero() {
+		followUp.Offset = n.Occurrence.Sub(sendAt)
+		setNotifyOffset(data, followUp.Offset)
+	}
+
+	if err := s.outbox.AddNotifications(ctx, s.db, []*model.ScheduledNotification{followUp}); err != nil {
+		return time.Time{}, fmt.Errorf("add notifications: %w", err)
+	}
+
+	return sendAt, nil
+}
+
+// AckReminder stops the remaining reminders of the user for the occurrence, including snoozed ones.
+func (s *Sender) AckReminder(ctx context.Context, userID int64, id int64) error {
+	n, err := s.getUserReminder(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	occurrence, ok := reminderOccurrence(n)
+	if !ok {
+		return fmt.Errorf("reminder %v has no occurrence", id)
+	}
+
+	if err := s.reminders.AddReminderAck(ctx, s.db, &model.ReminderAck{
+		UserID:     n.UserID,
+		EventID:    n.EventID,
+		Occurrence: occurrence,
+	}); err != nil {
+		return fmt.Errorf("add reminder ack: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Sender) getUserReminder(ctx context.Context, userID int64, id int64) (*model.ScheduledNotification, error) {
+	n, err := s.outbox.GetNotification(ctx, s.db, id)
+	if err != nil {
+		if errors.Is(err, model.ErrNoRecord) {
+			return nil, model.ErrNoRecord
+		}
+		return nil, fmt.Errorf("get notification: %w", err)
+	}
+
+	if n.UserID != userID || n.Kind != model.NotificationKindReminder {
+		return nil, model.ErrNoRecord
+	}
+
+	return n, nil
+}
+
+type ackKey struct {
+	userID     int64
+	eventID    int64
+	occurrence int64
+}
+
+// getAckedReminders returns ids of reminders, occurrences of which were acknowledged by the user.
+func (s *Sender) getAckedReminders(ctx context.Context, notifications []*model.ScheduledNotification) (map[int64]struct{}, error) {
+	var userIDs, eventIDs []int64
+	for _, n := range notifications {
+		if n.Kind == model.NotificationKindReminder {
+			userIDs = append(userIDs, n.UserID)
+			eventIDs = append(eventIDs, n.EventID)
+		}
+	}
+
+	res := make(map[int64]struct{})
+	if len(eventIDs) == 0 {
+		return res, nil
+	}
+
+	acks, err := s.reminders.GetReminderAcks(ctx, s.db, model.ReminderAcksFilter{
+		UserIDs:  userIDs,
+		EventIDs: eventIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	acked := make(map[ackKey]struct{}, len(acks))
+	for _, a := range acks {
+		acked[ackKey{a.UserID, a.EventID, a.Occurrence.Unix()}] = struct{}{}
+	}
+
+	for _, n := range notifications {
+		if n.Kind != model.NotificationKindReminder {
+			continue
+		}
+
+		occurrence, ok := reminderOccurrence(n)
+		if !ok {
+			continue
+		}
+
+		if _, ok := acked[ackKey{n.UserID, n.EventID, occurrence.Unix()}]; ok {
+			res[n.ID] = struct{}{}
+		}
+	}
+
+	return res, nil
+}
+
+// reminderOccurrence returns the occurrence the reminder is acknowledged by, reminders at the absolute
+// time and their follow-ups are identified by the original time.
+func reminderOccurrence(n *model.ScheduledNotification) (time.Time, bool) {
+	if !n.Occurrence.IsZero() {
+		return n.Occurrence, true
+	}
+
+	notifyAt, err := time.Parse(time.RFC3339, n.Data["notify_at"])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return notifyAt, true
+}
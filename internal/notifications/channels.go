@@ -0,0 +1,313 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/fcm"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/sms"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/webhook"
+	"golang.org/x/sync/errgroup"
+)
+
+// sendConcurrency limits requests of channels that send messages one by one.
+const sendConcurrency = 8
+
+// pushTextLimits caps user provided texts in bytes, so that the push payload stays within 4 KB.
+var pushTextLimits = map[string]int{
+	"event_title": 256,
+	"group_name":  256,
+	"summary":     2048,
+}
+
+// Channel delivers messages to users in one way.
+type Channel interface {
+	Type() model.Channel
+	// Send returns results in the order of messages, the error means that none of them was sent.
+	Send(ctx context.Context, ms []*Message) ([]*Result, error)
+}
+
+// Message is addressed by the push token, email, phone number or URL depending on the channel.
+// Title and Body are rendered for the user, Data is the payload of the push.
+type Message struct {
+	UserID  int64
+	Address string
+	Title   string
+	Body    string
+	Data    map[string]string
+}
+
+// Result is the outcome of sending a single message.
+type Result struct {
+	Err error
+	// Retryable reports that the message can be sent later.
+	Retryable bool
+	// InvalidAddress reports that the address was rejected and must not be used anymore.
+	InvalidAddress bool
+}
+
+type fcmService interface {
+	SendMessageBatch(ctx context.Context, ms []*fcm.Message) ([]*fcm.Result, error)
+}
+
+type fcmChannel struct {
+	fcm fcmService
+}
+
+func NewFCMChannel(fcm fcmService) Channel {
+	return &fcmChannel{fcm: fcm}
+}
+
+func (*fcmChannel) Type() model.Channel {
+	return model.ChannelPush
+}
+
+func (c *fcmChannel) Send(ctx context.Context, ms []*Message) ([]*Result, error) {
+	messages := make([]*fcm.Message, len(ms))
+	for i, m := range ms {
+		messages[i] = &fcm.Message{
+			Token: m.Address,
+			Data:  truncatePushData(m.Data),
+		}
+	}
+
+	results, err := c.fcm.SendMessageBatch(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Result, len(results))
+	for i, r := range results {
+		res[i] = &Result{
+			Err:            r.Err,
+			Retryable:      r.Retryable,
+			InvalidAddress: r.InvalidToken,
+		}
+	}
+
+	return res, nil
+}
+
+// truncatePushData returns a copy of data with long texts cut.
+func truncatePushData(data map[string]string) map[string]string {
+	res := make(map[string]string, len(data))
+	for k, v := range data {
+		if limit, ok := pushTextLimits[k]; ok {
+			v = truncateText(v, limit)
+		}
+		res[k] = v
+	}
+
+	return res
+}
+
+// truncateText cuts s to at most limit bytes on the rune boundary and marks the cut with an ellipsis.
+func truncateText(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	const ellipsis = "…"
+	cut := limit - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + ellipsis
+}
+
+type mailService interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type emailChannel struct {
+	mail mailService
+}
+
+func NewEmailChannel(mail mailService) Channel {
+	return &emailChannel{mail: mail}
+}
+
+func (*emailChannel) Type() model.Channel {
+	return model.ChannelEmail
+}
+
+func (c *emailChannel) Send(ctx context.Context, ms []*Message) ([]*Result, error) {
+	return sendEach(ctx, ms, func(ctx context.Context, m *Message) *Result {
+		err := c.mail.Send(ctx, m.Address, m.Title, m.Body)
+		if err == nil {
+			return &Result{}
+		}
+
+		// network errors and 4xx replies are temporary, 550, 551 and 553 reject the mailbox
+		var smtpErr *textproto.Error
+		if !errors.As(err, &smtpErr) {
+			return &Result{Err: err, Retryable: true}
+		}
+
+		return &Result{
+			Err:            err,
+			Retryable:      smtpErr.Code >= 400 && smtpErr.Code < 500,
+			InvalidAddress: smtpErr.Code == 550 || smtpErr.Code == 551 || smtpErr.Code == 553,
+		}
+	}), nil
+}
+
+type webhookClient interface {
+	Post(ctx context.Context, url string, payload interface{}) error
+}
+
+type webhookChannel struct {
+	client webhookClient
+}
+
+func NewWebhookChannel(client webhookClient) Channel {
+	return &webhookChannel{client: client}
+}
+
+func (*webhookChannel) Type() model.Channel {
+	return model.ChannelWebhook
+}
+
+func (c *webhookChannel) Send(ctx context.Context, ms []*Message) ([]*Result, error) {
+	return sendEach(ctx, ms, func(ctx context.Context, m *Message) *Result {
+		err := c.client.Post(ctx, m.Address, &struct {
+			UserID int64             `json:"user_id"`
+			Title  string            `json:"title"`
+			Body   string            `json:"body"`
+			Data   map[string]string `json:"data"`
+		}{
+			UserID: m.UserID,
+			Title:  m.Title,
+			Body:   m.Body,
+			Data:   m.Data,
+		})
+		if err == nil {
+			return &Result{}
+		}
+
+		var statusErr *webhook.StatusError
+		if !errors.As(err, &statusErr) {
+			return &Result{Err: err, Retryable: true}
+		}
+
+		return &Result{
+			Err:            err,
+			Retryable:      retryableStatus(statusErr.Code),
+			InvalidAddress: statusErr.Code == http.StatusNotFound || statusErr.Code == http.StatusGone,
+		}
+	}), nil
+}
+
+type smsClient interface {
+	Send(ctx context.Context, to, text string) error
+}
+
+type smsChannel struct {
+	client smsClient
+}
+
+func NewSMSChannel(client smsClient) Channel {
+	return &smsChannel{client: client}
+}
+
+func (*smsChannel) Type() model.Channel {
+	return model.ChannelSMS
+}
+
+func (c *smsChannel) Send(ctx context.Context, ms []*Message) ([]*Result, error) {
+	return sendEach(ctx, ms, func(ctx context.Context, m *Message) *Result {
+		err := c.client.Send(ctx, m.Address, m.Title+"\n"+m.Body)
+		if err == nil {
+			return &Result{}
+		}
+
+		var statusErr *sms.StatusError
+		if !errors.As(err, &statusErr) {
+			return &Result{Err: err, Retryable: true}
+		}
+
+		return &Result{
+			Err:       err,
+			Retryable: retryableStatus(statusErr.Code),
+		}
+	}), nil
+}
+
+// logChannel writes messages as JSON lines instead of sending them, it replaces other channels
+// during development.
+type logChannel struct {
+	channel model.Channel
+	w       io.Writer
+}
+
+func NewLogChannel(channel model.Channel, w io.Writer) Channel {
+	return &logChannel{
+		channel: channel,
+		w:       w,
+	}
+}
+
+func (c *logChannel) Type() model.Channel {
+	return c.channel
+}
+
+func (c *logChannel) Send(_ context.Context, ms []*Message) ([]*Result, error) {
+	enc := json.NewEncoder(c.w)
+
+	res := make([]*Result, len(ms))
+	for i, m := range ms {
+		err := enc.Encode(&struct {
+			Time    time.Time         `json:"time"`
+			Channel model.Channel     `json:"channel"`
+			UserID  int64             `json:"user_id"`
+			Address string            `json:"address"`
+			Title   string            `json:"title"`
+			Body    string            `json:"body"`
+			Data    map[string]string `json:"data"`
+		}{
+			Time:    time.Now(),
+			Channel: c.channel,
+			UserID:  m.UserID,
+			Address: m.Address,
+			Title:   m.Title,
+			Body:    m.Body,
+			Data:    m.Data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("write message: %w", err)
+		}
+		res[i] = &Result{}
+	}
+
+	return res, nil
+}
+
+func sendEach(ctx context.Context, ms []*Message, send func(ctx context.Context, m *Message) *Result) []*Result {
+	res := make([]*Result, len(ms))
+
+	g := &errgroup.Group{}
+	g.SetLimit(sendConcurrency)
+	for i, m := range ms {
+		i, m := i, m
+		g.Go(func() error {
+			res[i] = send(ctx, m)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return res
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,222 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// maxDigestLines keeps the digest within the limit of the push payload.
+const maxDigestLines = 15
+
+// digest covers events in [from, to) and is sent at sendAt.
+type digest struct {
+	digestType model.DigestType
+	sendAt     time.Time
+	from       time.Time
+	to         time.Time
+}
+
+// scheduleDigests stores digests, local send time of which is in the interval, in the outbox.
+// A failed digest of one user doesn't prevent digests of others.
+func (s *Sender) scheduleDigests(ctx context.Context, from, to time.Time) error {
+	settings, err := s.digests.GetDigestSettings(ctx, s.db, model.DigestSettingsFilter{Enabled: true})
+	if err != nil {
+		return fmt.Errorf("get digest settings: %w", err)
+	}
+
+	if len(settings) == 0 {
+		return nil
+	}
+
+	userIDs := make([]int64, len(settings))
+	for i, st := range settings {
+		userIDs[i] = st.UserID
+	}
+
+	users, err := s.users.GetUsersByIDs(ctx, s.db, userIDs)
+	if err != nil {
+		return fmt.Errorf("get users: %w", err)
+	}
+
+	usersMap := make(map[int64]*model.User, len(users))
+	for _, u := range users {
+		usersMap[u.ID] = u
+	}
+
+	var scheduled []*model.ScheduledNotification
+	for _, st := range settings {
+		user, ok := usersMap[st.UserID]
+		if !ok || !user.Notify {
+			continue
+		}
+
+		loc := userLocation(user)
+		for _, d := range getDueDigests(st, loc, from, to) {
+			n, err := s.buildDigest(ctx, user, st, d, loc)
+			if err != nil {
+				s.logger.Errorw("failed to build digest", "user_id", user.ID, "type", d.digestType, "error", err)
+				continue
+			}
+			if n != nil {
+				scheduled = append(scheduled, n)
+			}
+		}
+	}
+
+	if err := s.outbox.AddNotifications(ctx, s.db, scheduled); err != nil {
+		return fmt.Errorf("add notifications: %w", err)
+	}
+
+	return nil
+}
+
+// getDueDigests checks every local day the interval touches, as it can cross the local midnight.
+func getDueDigests(st *model.DigestSettings, loc *time.Location, from, to time.Time) []*digest {
+	var res []*digest
+
+	localFrom := from.In(loc)
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if st.Daily {
+			sendAt := atMinutes(day, st.DailyTime)
+			if !sendAt.Before(from) && sendAt.Before(to) {
+				res = append(res, &digest{
+					digestType: model.DigestTypeDaily,
+					sendAt:     sendAt,
+					from:       day,
+					to:         day.AddDate(0, 0, 1),
+				})
+			}
+		}
+
+		if st.Weekly && day.Weekday() == st.WeeklyDay {
+			sendAt := atMinutes(day, st.WeeklyTime)
+			if !sendAt.Before(from) && sendAt.Before(to) {
+				res = append(res, &digest{
+					digestType: model.DigestTypeWeekly,
+					sendAt:     sendAt,
+					from:       day.AddDate(0, 0, 1),
+					to:         day.AddDate(0, 0, 8),
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+func atMinutes(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// buildDigest returns nil if there are no events to tell about.
+func (s *Sender) buildDigest(
+	ctx context.Context,
+	user *model.User,
+	st *model.DigestSettings,
+	d *digest,
+	loc *time.Location,
+) (*model.ScheduledNotification, error) {
+	groups, err := s.groups.GetUserGroups(ctx, s.db, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get user groups: %w", err)
+	}
+
+	selected := make(map[int64]struct{}, len(st.GroupIDs))
+	for _, id := range st.GroupIDs {
+		selected[id] = struct{}{}
+	}
+
+	var groupIDs []int64
+	for _, g := range groups {
+		if _, ok := selected[g.ID]; ok || len(selected) == 0 {
+			groupIDs = append(groupIDs, g.ID)
+		}
+	}
+
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	events, err := s.eventsService.GetEvents(ctx, model.EventsFilter{
+		From:     d.from,
+		To:       d.to,
+		GroupIDs: groupIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].From.Before(events[j].From)
+	})
+
+	title := fmt.Sprintf("Today: %s", countEvents(len(events)))
+	if d.digestType == model.DigestTypeWeekly {
+		title = fmt.Sprintf("Week ahead: %s", countEvents(len(events)))
+	}
+
+	channels := []model.Channel{model.ChannelPush}
+	if st.Email {
+		channels = append(channels, model.ChannelEmail)
+	}
+
+	return &model.ScheduledNotification{
+		Kind:   model.NotificationKindDigest,
+		UserID: user.ID,
+		SendAt: d.sendAt,
+		Data: map[string]string{
+			"digest":       string(d.digestType),
+			"date":         d.from.Format("2006-01-02"),
+			"events_count": fmt.Sprintf("%v", len(events)),
+			"title":        title,
+			"summary":      renderDigest(events, d.digestType, loc),
+		},
+		Channels: channels,
+	}, nil
+}
+
+// renderDigest lists events one per line, weekly digest prefixes them with the weekday.
+func renderDigest(events []*model.Event, digestType model.DigestType, loc *time.Location) string {
+	lines := make([]string, 0, maxDigestLines+1)
+	for i, e := range events {
+		if i == maxDigestLines {
+			lines = append(lines, fmt.Sprintf("and %d more", len(events)-maxDigestLines))
+			break
+		}
+
+		start := e.From.In(loc)
+
+		var when string
+		switch {
+		case digestType == model.DigestTypeWeekly && e.AllDay:
+			when = start.Format("Mon")
+		case digestType == model.DigestTypeWeekly:
+			when = start.Format("Mon 15:04")
+		case e.AllDay:
+			when = "All day"
+		default:
+			when = start.Format("15:04")
+		}
+
+		lines = append(lines, when+" "+e.Title)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func countEvents(n int) string {
+	if n == 1 {
+		return "1 event"
+	}
+	return fmt.Sprintf("%d events", n)
+}
@@ -0,0 +1,405 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+const claimLimit = 500
+
+// deliveryStats counts outcomes of the dispatched reminders.
+type deliveryStats struct {
+	sent          int
+	dropped       int
+	cancelled     int
+	deferred      int
+	failed        int
+	retrying      int
+	invalidTokens int
+}
+
+func (d *deliveryStats) add(other *deliveryStats) {
+	d.sent += other.sent
+	d.dropped += other.dropped
+	d.cancelled += other.cancelled
+	d.deferred += other.deferred
+	d.failed += other.failed
+	d.retrying += other.retrying
+	d.invalidTokens += other.invalidTokens
+}
+
+// dispatchNotifications sends reminders from the outbox that are due before the given time.
+// Reminders, that failed with retryable errors or were claimed by a crashed instance, are claimed
+// again after the claim timeout.
+func (s *Sender) dispatchNotifications(ctx context.Context, before time.Time) error {
+	stats := &deliveryStats{}
+	defer func() {
+		if *stats != (deliveryStats{}) {
+			s.logger.Infow("notifications dispatched",
+				"sent", stats.sent,
+				"dropped", stats.dropped,
+				"cancelled", stats.cancelled,
+				"deferred", stats.deferred,
+				"failed", stats.failed,
+				"retrying", stats.retrying,
+				"invalid_tokens", stats.invalidTokens,
+			)
+		}
+	}()
+
+	for {
+		now := time.Now()
+
+		notifications, err := s.outbox.ClaimNotifications(ctx, s.db, model.ClaimFilter{
+			SendBefore:    before,
+			SendAfter:     now.Add(-config.NotifyGracePeriod()),
+			ClaimedBefore: now.Add(-config.NotifyClaimTimeout()),
+			Limit:         claimLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("claim notifications: %w", err)
+		}
+
+		if len(notifications) == 0 {
+			return nil
+		}
+
+		batchStats, err := s.sendNotifications(ctx, notifications)
+		if err != nil {
+			return err
+		}
+		stats.add(batchStats)
+
+		if len(notifications) < claimLimit {
+			return nil
+		}
+	}
+}
+
+// sendNotifications sends every notification through the channels chosen by the user, push goes to all
+// devices of the user. A notification is sent if any message was delivered, it is retried only if none
+// was delivered and some failed temporarily. Notifications due in quiet hours of the user or while
+// the user is snoozed are deferred until the quiet period ends or dropped, as the user chose.
+func (s *Sender) sendNotifications(ctx context.Context, notifications []*model.ScheduledNotification) (*deliveryStats, error) {
+	var userIDs []int64
+	userIDsMap := make(map[int64]struct{})
+
+	for _, n := range notifications {
+		if _, ok := userIDsMap[n.UserID]; !ok {
+			userIDs = append(userIDs, n.UserID)
+			userIDsMap[n.UserID] = struct{}{}
+		}
+	}
+
+	users, err := s.users.GetUsersByIDs(ctx, s.db, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+
+	usersMap := make(map[int64]*model.User, len(users))
+	for _, u := range users {
+		usersMap[u.ID] = u
+	}
+
+	devices, err := s.devices.GetUsersDevices(ctx, s.db, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get devices: %w", err)
+	}
+
+	tokens := getPushTokens(users, devices)
+
+	acked, err := s.getAckedReminders(ctx, notifications)
+	if err != nil {
+		return nil, fmt.Errorf("get acked reminders: %w", err)
+	}
+
+	stats := &deliveryStats{}
+	now := time.Now()
+
+	var sentIDs, failedIDs, droppedIDs, cancelledIDs []int64
+	deferred := make(map[time.Time][]int64)
+	messages := make(map[model.Channel][]*Message)
+	messageNotifications := make(map[model.Channel][]int)
+	addressed := make(map[int]bool)
+	for i, n := range notifications {
+		if _, ok := acked[n.ID]; ok {
+			cancelledIDs = append(cancelledIDs, n.ID)
+			stats.cancelled++
+			continue
+		}
+
+		// reminder id lets the client snooze or acknowledge the reminder
+		if n.Kind == model.NotificationKindReminder {
+			n.Data["reminder_id"] = strconv.FormatInt(n.ID, 10)
+		}
+
+		user, ok := usersMap[n.UserID]
+
+		quiet := false
+		if ok && user.Notify {
+			var until time.Time
+			until, quiet = quietUntil(user, now)
+			if quiet && user.Quiet.Policy == model.QuietPolicyDefer {
+				deferred[until] = append(deferred[until], n.ID)
+				stats.deferred++
+				continue
+			}
+		}
+
+		if ok && user.Notify && !quiet {
+			channels := user.Channels
+			if n.Channels != nil {
+				channels = n.Channels
+			}
+
+			title, body := renderMessage(n.Data, userLocation(user))
+			for _, c := range channels {
+				if _, ok := s.channels[c]; !ok {
+					continue
+				}
+
+				for _, address := range getAddresses(c, user, tokens) {
+					messages[c] = append(messages[c], &Message{
+						UserID:  user.ID,
+						Address: address,
+						Title:   title,
+						Body:    body,
+						Data:    n.Data,
+					})
+					messageNotifications[c] = append(messageNotifications[c], i)
+					addressed[i] = true
+				}
+			}
+		}
+
+		// user could have signed out or disabled notifications after they were scheduled, such notifications
+		// are dropped, so are the ones in quiet hours with the drop policy
+		if !addressed[i] {
+			droppedIDs = append(droppedIDs, n.ID)
+			stats.dropped++
+		}
+	}
+
+	delivered := make(map[int]bool)
+	retryable := make(map[int]bool)
+	var invalidTokens []string
+	for c, ms := range messages {
+		results, err := s.channels[c].Send(ctx, ms)
+		if err != nil {
+			s.logger.Errorw("failed to send notifications", "channel", c, "error", err)
+			for _, n := range messageNotifications[c] {
+				retryable[n] = true
+			}
+			continue
+		}
+
+		for i, r := range results {
+			n := messageNotifications[c][i]
+			switch {
+			case r.Err == nil:
+				delivered[n] = true
+			case r.Retryable:
+				s.logger.Warnw("failed to send notification", "id", notifications[n].ID, "channel", c, "error", r.Err)
+				retryable[n] = true
+			case r.InvalidAddress && c == model.ChannelPush:
+				invalidTokens = append(invalidTokens, ms[i].Address)
+				stats.invalidTokens++
+			default:
+				s.logger.Errorw("failed to send notification", "id", notifications[n].ID, "channel", c, "error", r.Err)
+			}
+		}
+	}
+
+	for i, n := range notifications {
+		if !addressed[i] {
+			continue
+		}
+
+		switch {
+		case delivered[i]:
+			sentIDs = append(sentIDs, n.ID)
+			stats.sent++
+		case retryable[i]:
+			// notification stays claimed and is sent again after the claim timeout
+			stats.retrying++
+		default:
+			failedIDs = append(failedIDs, n.ID)
+			stats.failed++
+		}
+	}
+
+	if err := s.finishNotifications(ctx, notifications, sentIDs, failedIDs, droppedIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.outbox.MarkNotifications(ctx, s.db, cancelledIDs, model.NotificationStatusCancelled); err != nil {
+		return nil, fmt.Errorf("mark notifications cancelled: %w", err)
+	}
+
+	for sendAt, ids := range deferred {
+		if err := s.outbox.DeferNotifications(ctx, s.db, ids, sendAt); err != nil {
+			return nil, fmt.Errorf("defer notifications: %w", err)
+		}
+	}
+
+	if err := s.devices.DeleteDevicesByTokens(ctx, s.db, invalidTokens); err != nil {
+		return nil, fmt.Errorf("delete devices: %w", err)
+	}
+
+	return stats, nil
+}
+
+// finishNotifications sets the final status of processed notifications and puts them into the inbox,
+// so that users see notifications that were not delivered to any device too. Dropped notifications
+// get into the inbox as well, as the user turned off only the delivery, not the notifications themselves.
+func (s *Sender) finishNotifications(ctx context.Context, notifications []*model.ScheduledNotification, sentIDs, failedIDs, droppedIDs []int64) error {
+	finished := make(map[int64]struct{}, len(sentIDs)+len(failedIDs)+len(droppedIDs))
+	for _, ids := range [][]int64{sentIDs, failedIDs, droppedIDs} {
+		for _, id := range ids {
+			finished[id] = struct{}{}
+		}
+	}
+
+	var items []*model.InboxItem
+	for _, n := range notifications {
+		if _, ok := finished[n.ID]; !ok {
+			continue
+		}
+
+		items = append(items, &model.InboxItem{
+			NotificationID: n.ID,
+			UserID:         n.UserID,
+			Kind:           n.Kind,
+			GroupID:        n.GroupID,
+			EventID:        n.Data["event_id"],
+			Data:           n.Data,
+		})
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.outbox.MarkNotifications(ctx, tx, sentIDs, model.NotificationStatusSent); err != nil {
+		return fmt.Errorf("mark notifications sent: %w", err)
+	}
+
+	if err := s.outbox.MarkNotifications(ctx, tx, failedIDs, model.NotificationStatusFailed); err != nil {
+		return fmt.Errorf("mark notifications failed: %w", err)
+	}
+
+	if err := s.outbox.MarkNotifications(ctx, tx, droppedIDs, model.NotificationStatusDropped); err != nil {
+		return fmt.Errorf("mark notifications dropped: %w", err)
+	}
+
+	if err := s.inbox.AddInboxItems(ctx, tx, items); err != nil {
+		return fmt.Errorf("add inbox items: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// getPushTokens returns unique tokens of the registered devices of users that still want to be notified.
+func getPushTokens(users []*model.User, devices []*model.Device) map[int64][]string {
+	res := make(map[int64][]string, len(users))
+	seen := make(map[string]struct{})
+
+	add := func(userID int64, token string) {
+		if token == "" {
+			return
+		}
+		if _, ok := seen[token]; ok {
+			return
+		}
+		seen[token] = struct{}{}
+		res[userID] = append(res[userID], token)
+	}
+
+	notify := make(map[int64]bool, len(users))
+	for _, u := range users {
+		notify[u.ID] = u.Notify
+	}
+
+	for _, d := range devices {
+		if notify[d.UserID] {
+			add(d.UserID, d.PushToken)
+		}
+	}
+
+	return res
+}
+
+// getAddresses returns where the message of the channel is sent, channels without the address are skipped.
+func getAddresses(c model.Channel, user *model.User, tokens map[int64][]string) []string {
+	var address string
+	switch c {
+	case model.ChannelPush:
+		return tokens[user.ID]
+	case model.ChannelEmail:
+		address = user.Email
+	case model.ChannelWebhook:
+		address = user.WebhookURL
+	case model.ChannelSMS:
+		address = user.PhoneNumber
+	}
+
+	if address == "" {
+		return nil
+	}
+	return []string{address}
+}
+
+// cleanupNotifications expires reminders older than the grace period, removes old outbox entries,
+// acknowledgements of past occurrences, old inbox items and stale devices.
+func (s *Sender) cleanupNotifications(ctx context.Context) error {
+	now := time.Now()
+
+	if err := s.outbox.ExpireNotifications(ctx, s.db, now.Add(-config.NotifyGracePeriod())); err != nil {
+		return fmt.Errorf("expire notifications: %w", err)
+	}
+
+	// entries are kept at least for the grace period, so that reminders caught up on startup are not sent twice
+	retention := config.NotifyRetention()
+	if retention < config.NotifyGracePeriod() {
+		retention = config.NotifyGracePeriod()
+	}
+
+	if err := s.outbox.DeleteNotifications(ctx, s.db, now.Add(-retention)); err != nil {
+		return fmt.Errorf("delete notifications: %w", err)
+	}
+
+	if err := s.inbox.DeleteInboxItems(ctx, s.db, now.Add(-config.InboxRetention())); err != nil {
+		return fmt.Errorf("delete inbox items: %w", err)
+	}
+
+	if err := s.reminders.DeleteReminderAcks(ctx, s.db, now.Add(-retention)); err != nil {
+		return fmt.Errorf("delete reminder acks: %w", err)
+	}
+
+	// sessions that were not refreshed within the session TTL are expired, so are their devices
+	if err := s.devices.DeleteStaleDevices(ctx, s.db, now.Add(-config.SessionTTl())); err != nil {
+		return fmt.Errorf("delete stale devices: %w", err)
+	}
+
+	return nil
+}
+
+// cleanupChanges removes changes older than the retention, clients with older sync tokens do a full sync.
+func (s *Sender) cleanupChanges(ctx context.Context) error {
+	if err := s.changes.DeleteChanges(ctx, s.db, time.Now().Add(-config.ChangesRetention())); err != nil {
+		return fmt.Errorf("delete changes: %w", err)
+	}
+
+	return nil
+}
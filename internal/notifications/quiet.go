@@ -0,0 +1,51 @@
+package notifications
+
+import (
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+// quietUntil returns the end of the quiet period of the user at the given time. Snooze and quiet
+// hours can follow one another, so the end is moved until neither of them applies.
+func quietUntil(user *model.User, at time.Time) (time.Time, bool) {
+	until := at
+	for {
+		switch {
+		case user.Quiet.SnoozeUntil.After(until):
+			until = user.Quiet.SnoozeUntil
+		case inQuietHours(user, until):
+			until = quietHoursEnd(user, until)
+		default:
+			return until, until.After(at)
+		}
+	}
+}
+
+func inQuietHours(user *model.User, at time.Time) bool {
+	q := user.Quiet
+	if !q.Enabled || q.Start == q.End {
+		return false
+	}
+
+	local := at.In(userLocation(user))
+	m := local.Hour()*60 + local.Minute()
+
+	if q.Start < q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
+
+// quietHoursEnd returns the first end of quiet hours after the given time.
+func quietHoursEnd(user *model.User, at time.Time) time.Time {
+	local := at.In(userLocation(user))
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+
+	end := atMinutes(day, user.Quiet.End)
+	if !end.After(at) {
+		end = atMinutes(day.AddDate(0, 0, 1), user.Quiet.End)
+	}
+
+	return end
+}
@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+)
+
+const startLayout = "Mon, 02 Jan 2006 15:04 MST"
+
+var changeBodies = map[model.ChangeType]string{
+	model.ChangeTypeEventCreated:     "New event was added",
+	model.ChangeTypeEventRescheduled: "Event was rescheduled",
+	model.ChangeTypeEventCancelled:   "Event was cancelled",
+	model.ChangeTypeInstanceDeleted:  "Occurrence of the event was deleted",
+	model.ChangeTypeMembersAdded:     "New members were added to the group",
+	model.ChangeTypeMembersRemoved:   "Members were removed from the group",
+	model.ChangeTypeRolesChanged:     "Roles of members were changed",
+	model.ChangeTypeGroupRenamed:     "Group was renamed",
+}
+
+// renderMessage builds the text of the notification for channels that show it to the user as is.
+func renderMessage(data map[string]string, loc *time.Location) (string, string) {
+	if _, ok := data["digest"]; ok {
+		return data["title"], data["summary"]
+	}
+
+	if change, ok := data["change"]; ok {
+		if count, _ := strconv.Atoi(data["changes"]); count > 1 {
+			return "Group updates", fmt.Sprintf("%d changes were made in your group", count)
+		}
+
+		title := data["event_title"]
+		if name, ok := data["group_name"]; ok {
+			title = name
+		}
+		if title == "" {
+			title = "Group update"
+		}
+
+		return title, changeBodies[model.ChangeType(change)]
+	}
+
+	body := "Reminder"
+	if start, err := time.Parse(time.RFC3339, data["event_start"]); err == nil {
+		body = "Starts " + start.In(loc).Format(startLayout)
+		if v, ok := data["notify_offset"]; ok {
+			if offset, err := strconv.ParseInt(v, 10, 64); err == nil {
+				body = fmt.Sprintf("Starts %s, %s", formatOffset(time.Duration(offset)*time.Second), start.In(loc).Format(startLayout))
+			}
+		}
+	}
+
+	return data["event_title"], body
+}
+
+func formatOffset(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "now"
+	case d%(24*time.Hour) == 0:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64(d/time.Minute), "minute")
+	}
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "in 1 " + unit
+	}
+	return fmt.Sprintf("in %d %ss", n, unit)
+}
+
+// userLocation falls back to UTC, as the time zone of the user could be removed from tzdata.
+func userLocation(user *model.User) *time.Location {
+	loc, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -3,11 +3,13 @@ package notifications
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/SergeyKozhin/shared-planner-backend/internal/config"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/database"
 	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
-	"github.com/SergeyKozhin/shared-planner-backend/internal/pkg/fcm"
 	"github.com/xlab/closer"
 	"go.uber.org/zap"
 )
@@ -17,12 +19,19 @@ type Sender struct {
 	logger        *zap.SugaredLogger
 	groups        groupsRepository
 	users         usersRepository
+	reminders     remindersRepository
+	outbox        outboxRepository
+	devices       devicesRepository
+	inbox         inboxRepository
+	digests       digestsRepository
+	changes       changesRepository
 	eventsService eventsService
-	fcm           fcmService
+	channels      map[model.Channel]Channel
 }
 
 type groupsRepository interface {
 	GetGroups(ctx context.Context, q database.Queryable, ids []int64) ([]*model.Group, error)
+	GetUserGroups(ctx context.Context, q database.Queryable, userID int64) ([]*model.Group, error)
 	GetUserGroupSettings(ctx context.Context, q database.Queryable, filter model.UserGroupSettingsFilter) ([]*model.GroupSettings, error)
 }
 
@@ -30,13 +39,47 @@ type usersRepository interface {
 	GetUsersByIDs(ctx context.Context, q database.Queryable, ids []int64) ([]*model.User, error)
 }
 
-type eventsService interface {
-	GetEvents(ctx context.Context, filter model.EventsFilter) ([]*model.Event, error)
+type remindersRepository interface {
+	GetReminders(ctx context.Context, q database.Queryable, filter model.RemindersFilter) ([]*model.Reminders, error)
+	GetReminderAcks(ctx context.Context, q database.Queryable, filter model.ReminderAcksFilter) ([]*model.ReminderAck, error)
+	AddReminderAck(ctx context.Context, q database.Queryable, ack *model.ReminderAck) error
+	DeleteReminderAcks(ctx context.Context, q database.Queryable, before time.Time) error
+	GetDueSchedules(ctx context.Context, q database.Queryable, before time.Time) ([]*model.ReminderSchedule, error)
+}
+
+type outboxRepository interface {
+	AddNotifications(ctx context.Context, q database.Queryable, notifications []*model.ScheduledNotification) error
+	GetNotification(ctx context.Context, q database.Queryable, id int64) (*model.ScheduledNotification, error)
+	ClaimNotifications(ctx context.Context, q database.Queryable, filter model.ClaimFilter) ([]*model.ScheduledNotification, error)
+	MarkNotifications(ctx context.Context, q database.Queryable, ids []int64, status model.NotificationStatus) error
+	DeferNotifications(ctx context.Context, q database.Queryable, ids []int64, sendAt time.Time) error
+	ExpireNotifications(ctx context.Context, q database.Queryable, before time.Time) error
+	DeleteNotifications(ctx context.Context, q database.Queryable, before time.Time) error
+}
+
+type devicesRepository interface {
+	GetUsersDevices(ctx context.Context, q database.Queryable, userIDs []int64) ([]*model.Device, error)
+	DeleteDevicesByTokens(ctx context.Context, q database.Queryable, tokens []string) error
+	DeleteStaleDevices(ctx context.Context, q database.Queryable, before time.Time) error
+}
+
+type inboxRepository interface {
+	AddInboxItems(ctx context.Context, q database.Queryable, items []*model.InboxItem) error
+	DeleteInboxItems(ctx context.Context, q database.Queryable, before time.Time) error
+}
+
+type digestsRepository interface {
+	GetDigestSettings(ctx context.Context, q database.Queryable, filter model.DigestSettingsFilter) ([]*model.DigestSettings, error)
+}
+
+type changesRepository interface {
+	DeleteChanges(ctx context.Context, q database.Queryable, before time.Time) error
 }
 
-type fcmService interface {
-	SendMessage(ctx context.Context, m *fcm.Message) error
-	SendMessageBatch(ctx context.Context, ms []*fcm.Message) error
+type eventsService interface {
+	GetEvents(ctx context.Context, filter model.EventsFilter) ([]*model.Event, error)
+	GetSeries(ctx context.Context, filter model.EventsFilter) ([]*model.Series, error)
+	RefreshSchedules(ctx context.Context, ids []int64, after time.Time) error
 }
 
 func NewSender(
@@ -44,33 +87,50 @@ func NewSender(
 	logger *zap.SugaredLogger,
 	groups groupsRepository,
 	users usersRepository,
+	reminders remindersRepository,
+	outbox outboxRepository,
+	devices devicesRepository,
+	inbox inboxRepository,
+	digests digestsRepository,
+	changes changesRepository,
 	eventsService eventsService,
-	fcm fcmService,
+	channels []Channel,
 ) *Sender {
+	channelsMap := make(map[model.Channel]Channel, len(channels))
+	for _, c := range channels {
+		channelsMap[c.Type()] = c
+	}
+
 	return &Sender{
 		db:            db,
 		logger:        logger,
 		groups:        groups,
 		users:         users,
+		reminders:     reminders,
+		outbox:        outbox,
+		devices:       devices,
+		inbox:         inbox,
+		digests:       digests,
+		changes:       changes,
 		eventsService: eventsService,
-		fcm:           fcm,
+		channels:      channelsMap,
 	}
 }
 
 func (s *Sender) Start(ctx context.Context) {
 	now := time.Now()
 
-	from := now.Truncate(time.Minute)
-	to := from.Add(time.Minute)
-	// initial send
-	go s.findAndSendNotifications(ctx, from, to)
+	// reminders missed while no instance was running are caught up within the grace period
+	from := now.Add(-config.NotifyGracePeriod())
+	to := now.Truncate(time.Minute).Add(time.Minute)
+	s.processNotifications(ctx, from, to)
 
-	time.Sleep(to.Sub(time.Now()))
+	time.Sleep(time.Until(to))
 
 	// send at first minute
 	from = to
 	to = time.Now().Truncate(time.Minute).Add(time.Minute)
-	go s.findAndSendNotifications(ctx, from, to)
+	s.processNotifications(ctx, from, to)
 
 	ticker := time.NewTicker(time.Minute)
 	done := make(chan bool)
@@ -83,68 +143,275 @@ func (s *Sender) Start(ctx context.Context) {
 	for {
 		select {
 		case <-done:
-			break
+			return
 		case t := <-ticker.C:
 			from = to
 			to = t.Truncate(time.Minute).Add(time.Minute)
-			go s.findAndSendNotifications(ctx, from, to)
+			s.processNotifications(ctx, from, to)
 		}
 	}
 }
 
+// processNotifications stores reminders and digests of the interval in the outbox and sends the due ones.
+// Intervals of several instances may overlap, as reminders are scheduled and claimed only once.
+func (s *Sender) processNotifications(ctx context.Context, from, to time.Time) {
+	s.logger.Debugw("processing notifications", "from", from, "to", to)
+
+	if err := s.scheduleNotifications(ctx, from, to); err != nil {
+		s.logger.Errorw("failed to schedule notifications", "from", from, "to", to, "error", err)
+	}
+
+	if err := s.scheduleDigests(ctx, from, to); err != nil {
+		s.logger.Errorw("failed to schedule digests", "from", from, "to", to, "error", err)
+	}
+
+	// already scheduled reminders are sent even if scheduling failed
+	if err := s.dispatchNotifications(ctx, to); err != nil {
+		s.logger.Errorw("failed to dispatch notifications", "error", err)
+	}
+
+	if err := s.cleanupNotifications(ctx); err != nil {
+		s.logger.Errorw("failed to clean up notifications", "error", err)
+	}
+
+	if err := s.cleanupChanges(ctx); err != nil {
+		s.logger.Errorw("failed to clean up changes", "error", err)
+	}
+}
+
+// notification is sent to the user either notify before the start of the event or at the absolute time notifyAt.
 type notification struct {
-	event  *model.Event
-	notify time.Duration
+	event    *model.Event
+	userID   int64
+	notify   time.Duration
+	notifyAt time.Time
 }
 
-func (s *Sender) findAndSendNotifications(ctx context.Context, from, to time.Time) {
-	s.logger.Debugw("sending notifications", "from", from, "to", to)
+type remindersKey struct {
+	userID  int64
+	eventID int64
+}
 
-	// min notify 5 minutes
-	from = from.Add(5 * time.Minute)
-	// max notify 1 day
-	to = to.Add(24 * time.Hour)
+// scheduleNotifications stores reminders of the interval in the outbox. Only series with reminders due
+// before the end of the interval are expanded, their schedule is moved past the interval afterwards.
+func (s *Sender) scheduleNotifications(ctx context.Context, from, to time.Time) error {
+	due, err := s.reminders.GetDueSchedules(ctx, s.db, to)
+	if err != nil {
+		return fmt.Errorf("get due schedules: %w", err)
+	}
 
-	filter := model.EventsFilter{
-		From:     from,
-		To:       to,
-		GroupIDs: nil,
+	// reminders that became due before the interval, e.g. of the event created just now,
+	// are caught up within the grace period
+	start := from
+	var ids []int64
+	idsMap := make(map[int64]struct{})
+	// events are expanded only as far as the largest due offset, schedules seeded
+	// by the migration don't know their offsets yet
+	var maxOffset time.Duration
+	for _, d := range due {
+		if _, ok := idsMap[d.EventID]; !ok {
+			ids = append(ids, d.EventID)
+			idsMap[d.EventID] = struct{}{}
+		}
+		if d.NextAt.Before(start) {
+			start = d.NextAt
+		}
+		switch {
+		case d.Offset < 0:
+			maxOffset = config.MaxNotifyOffset()
+		case d.Offset > maxOffset:
+			maxOffset = d.Offset
+		}
 	}
-	events, err := s.eventsService.GetEvents(ctx, filter)
+
+	if catchUp := time.Now().Add(-config.NotifyGracePeriod()); start.Before(catchUp) {
+		start = catchUp
+		if from.Before(start) {
+			start = from
+		}
+	}
+
+	var events []*model.Event
+	if len(ids) != 0 {
+		// events starting later than the max offset can't have notifications in the interval
+		events, err = s.eventsService.GetEvents(ctx, model.EventsFilter{
+			From: start,
+			To:   to.Add(maxOffset),
+			IDs:  ids,
+		})
+		if err != nil {
+			return fmt.Errorf("get events: %w", err)
+		}
+	}
+
+	series, err := s.getSeriesWithNotifyAt(ctx, from, to)
 	if err != nil {
-		s.logger.Errorw("failed to get events", "filter", filter)
-		return
+		return fmt.Errorf("get series with notifications: %w", err)
 	}
 
-	notifications := getPossibleNotifications(events, from, to)
+	for _, sr := range series {
+		events = append(events, sr.Event)
+	}
 
-	groups, err := s.getGroups(ctx, notifications)
+	groups, err := s.getGroups(ctx, events)
 	if err != nil {
-		s.logger.Errorw("failed to get groups")
-		return
+		return fmt.Errorf("get groups: %w", err)
 	}
 
 	users, settings, err := s.getUsersAndSettings(ctx, groups)
 	if err != nil {
-		s.logger.Errorw("failed to get users and settings")
-		return
+		return fmt.Errorf("get users and settings: %w", err)
 	}
 
-	if err := s.sendNotifications(ctx, notifications, groups, users, settings); err != nil {
-		s.logger.Errorw("failed to send notifications: %w", err)
+	reminders, err := s.getReminders(ctx, events)
+	if err != nil {
+		return fmt.Errorf("get reminders: %w", err)
 	}
+
+	notifications := getPossibleNotifications(events, groups, reminders, start, to)
+
+	if err := s.outbox.AddNotifications(ctx, s.db, s.buildNotifications(notifications, users, settings)); err != nil {
+		return fmt.Errorf("add notifications: %w", err)
+	}
+
+	// schedule that failed to move stays due, reminders of the interval are not stored twice
+	if err := s.eventsService.RefreshSchedules(ctx, ids, to); err != nil {
+		return fmt.Errorf("refresh schedules: %w", err)
+	}
+
+	return nil
 }
 
-func getPossibleNotifications(events []*model.Event, from, to time.Time) []*notification {
+// getSeriesWithNotifyAt returns series that have either default or personal absolute reminders in the interval.
+func (s *Sender) getSeriesWithNotifyAt(ctx context.Context, from, to time.Time) ([]*model.Series, error) {
+	series, err := s.eventsService.GetSeries(ctx, model.EventsFilter{
+		NotifyFrom: from,
+		NotifyTo:   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get series: %w", err)
+	}
+
+	reminders, err := s.reminders.GetReminders(ctx, s.db, model.RemindersFilter{
+		NotifyFrom: from,
+		NotifyTo:   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get reminders: %w", err)
+	}
+
+	found := make(map[string]struct{}, len(series))
+	for _, sr := range series {
+		found[sr.Event.ID] = struct{}{}
+	}
+
+	var ids []int64
+	for _, r := range reminders {
+		id := strconv.FormatInt(r.EventID, 10)
+		if _, ok := found[id]; !ok {
+			found[id] = struct{}{}
+			ids = append(ids, r.EventID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return series, nil
+	}
+
+	personal, err := s.eventsService.GetSeries(ctx, model.EventsFilter{IDs: ids})
+	if err != nil {
+		return nil, fmt.Errorf("get series: %w", err)
+	}
+
+	return append(series, personal...), nil
+}
+
+// getReminders returns personal reminders for the events mapped by user and series id.
+func (s *Sender) getReminders(ctx context.Context, events []*model.Event) (map[remindersKey]*model.Reminders, error) {
+	var ids []int64
+	idsMap := make(map[int64]struct{})
+
+	for _, e := range events {
+		id, err := seriesID(e)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := idsMap[id]; !ok {
+			ids = append(ids, id)
+			idsMap[id] = struct{}{}
+		}
+	}
+
+	res := make(map[remindersKey]*model.Reminders)
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	reminders, err := s.reminders.GetReminders(ctx, s.db, model.RemindersFilter{EventIDs: ids})
+	if err != nil {
+		return nil, fmt.Errorf("get reminders: %w", err)
+	}
+
+	for _, r := range reminders {
+		res[remindersKey{userID: r.UserID, eventID: r.EventID}] = r
+	}
+
+	return res, nil
+}
+
+// getPossibleNotifications builds notifications for every member of the event group. Offsets are taken
+// from expanded occurrences, while absolute reminders are taken from series, that have no recurrence id.
+func getPossibleNotifications(
+	events []*model.Event,
+	groups map[int64]*model.Group,
+	reminders map[remindersKey]*model.Reminders,
+	from, to time.Time,
+) []*notification {
 	var res []*notification
 	for _, e := range events {
-		for _, n := range e.Notifications {
-			notifyTime := e.From.Add(-n)
-			if !notifyTime.Before(from) && notifyTime.Before(to) {
-				res = append(res, &notification{
-					event:  e,
-					notify: n,
-				})
+		group, ok := groups[e.GroupID]
+		if !ok {
+			continue
+		}
+
+		// ids were already checked while getting reminders
+		id, _ := seriesID(e)
+		isSeries := !strings.Contains(e.ID, "_")
+
+		for _, userID := range group.UsersIDs {
+			notifications, notifyAt := e.Notifications, e.NotifyAt
+			if r, ok := reminders[remindersKey{userID: userID, eventID: id}]; ok {
+				if r.Muted {
+					continue
+				}
+				if r.Custom {
+					notifications, notifyAt = r.Notifications, r.NotifyAt
+				}
+			}
+
+			if isSeries {
+				for _, t := range notifyAt {
+					if !t.Before(from) && t.Before(to) {
+						res = append(res, &notification{
+							event:    e,
+							userID:   userID,
+							notifyAt: t,
+						})
+					}
+				}
+				continue
+			}
+
+			for _, n := range notifications {
+				notifyTime := e.From.Add(-n)
+				if !notifyTime.Before(from) && notifyTime.Before(to) {
+					res = append(res, &notification{
+						event:  e,
+						userID: userID,
+						notify: n,
+					})
+				}
 			}
 		}
 	}
@@ -152,14 +419,25 @@ func getPossibleNotifications(events []*model.Event, from, to time.Time) []*noti
 	return res
 }
 
-func (s *Sender) getGroups(ctx context.Context, notification []*notification) (map[int64]*model.Group, error) {
+// seriesID returns id of the stored event both for series and their occurrences.
+func seriesID(e *model.Event) (int64, error) {
+	id, _, _ := strings.Cut(e.ID, "_")
+	res, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse event id %q: %w", e.ID, err)
+	}
+
+	return res, nil
+}
+
+func (s *Sender) getGroups(ctx context.Context, events []*model.Event) (map[int64]*model.Group, error) {
 	var groupIDs []int64
 	groupIDsMap := make(map[int64]struct{})
 
-	for _, n := range notification {
-		if _, ok := groupIDsMap[n.event.GroupID]; !ok {
-			groupIDs = append(groupIDs, n.event.GroupID)
-			groupIDsMap[n.event.GroupID] = struct{}{}
+	for _, e := range events {
+		if _, ok := groupIDsMap[e.GroupID]; !ok {
+			groupIDs = append(groupIDs, e.GroupID)
+			groupIDsMap[e.GroupID] = struct{}{}
 		}
 	}
 
@@ -211,6 +489,9 @@ func (s *Sender) getUsersAndSettings(ctx context.Context, groups map[int64]*mode
 		UserIDs:  userIDs,
 		GroupIDs: groupIDs,
 	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("get user group settings: %w", err)
+	}
 
 	settingsMap := make(map[int64][]*model.GroupSettings)
 	for _, s := range settings {
@@ -220,61 +501,81 @@ func (s *Sender) getUsersAndSettings(ctx context.Context, groups map[int64]*mode
 	return usersMap, settingsMap, nil
 }
 
-func (s *Sender) sendNotifications(
-	ctx context.Context,
+// buildNotifications skips users that don't want to be notified and prepares messages for the outbox.
+func (s *Sender) buildNotifications(
 	notifications []*notification,
-	groups map[int64]*model.Group,
 	users map[int64]*model.User,
 	settings map[int64][]*model.GroupSettings,
-) error {
-	var messages []*fcm.Message
+) []*model.ScheduledNotification {
+	var res []*model.ScheduledNotification
 	for _, n := range notifications {
-		group, ok := groups[n.event.GroupID]
+		user, ok := users[n.userID]
 		if !ok {
-			return fmt.Errorf("group not found %v", n.event.GroupID)
+			s.logger.Errorw("user not found", "user_id", n.userID)
+			continue
+		}
+		if !user.Notify {
+			continue
 		}
 
-		for _, userID := range group.UsersIDs {
-			user, ok := users[userID]
-			if !ok {
-				return fmt.Errorf("user not found %v", userID)
-			}
-			if !user.Notify || user.PushToken == "" {
-				continue
-			}
-
-			var groupSettings *model.GroupSettings
-			for _, s := range settings[userID] {
-				if s.GroupID == group.ID {
-					groupSettings = s
-				}
-			}
-			if groupSettings == nil {
-				return fmt.Errorf("user group settings not found %v %v", userID, group.ID)
-			}
-			if !groupSettings.Notify {
-				continue
+		var groupSettings *model.GroupSettings
+		for _, s := range settings[n.userID] {
+			if s.GroupID == n.event.GroupID {
+				groupSettings = s
 			}
+		}
+		if groupSettings == nil {
+			s.logger.Errorw("user group settings not found", "user_id", n.userID, "group_id", n.event.GroupID)
+			continue
+		}
+		if !groupSettings.Notify {
+			continue
+		}
 
-			notificationType, err := mapToDurationValue(n.notify)
-			if err != nil {
-				return fmt.Errorf("map notification type: %w", err)
-			}
-			messages = append(messages, &fcm.Message{
-				Token: user.PushToken,
-				Data: map[string]string{
-					"event_type":        fmt.Sprintf("%v", n.event.EventType),
-					"notification_type": fmt.Sprintf("%v", notificationType),
-					"event_title":       n.event.Title,
-					"group_id":          fmt.Sprintf("%v", n.event.GroupID),
-				},
-			})
+		// ids were already checked while getting reminders
+		eventID, _ := seriesID(n.event)
+
+		scheduled := &model.ScheduledNotification{
+			Kind:    model.NotificationKindReminder,
+			UserID:  n.userID,
+			GroupID: n.event.GroupID,
+			EventID: eventID,
+			Data: map[string]string{
+				"event_id":    n.event.ID,
+				"event_type":  fmt.Sprintf("%v", n.event.EventType),
+				"event_title": n.event.Title,
+				"group_id":    fmt.Sprintf("%v", n.event.GroupID),
+			},
+		}
+		if n.notifyAt.IsZero() {
+			scheduled.Occurrence = n.event.From
+			scheduled.Offset = n.notify
+			scheduled.SendAt = n.event.From.Add(-n.notify)
+			setNotifyOffset(scheduled.Data, n.notify)
+			scheduled.Data["event_start"] = n.event.From.Format(time.RFC3339)
+		} else {
+			scheduled.SendAt = n.notifyAt
+			scheduled.Data["notify_at"] = n.notifyAt.Format(time.RFC3339)
+			// absolute reminders belong to the series, they link to its first occurrence
+			scheduled.Data["event_id"] = fmt.Sprintf("%v_%v", eventID, n.event.From.Unix())
 		}
-	}
 
-	if err := s.fcm.SendMessageBatch(ctx, messages); err != nil {
-		return fmt.Errorf("send notifications")
+		res = append(res, scheduled)
 	}
 
-	return nil
+	return res
+}
+
+// setNotifyOffset puts the offset of the reminder into the push data, old clients parse
+// notification_type instead, so it is kept for offsets they know.
+func setNotifyOffset(data map[string]string, offset time.Duration) {
+	data["notify_offset"] = fmt.Sprintf("%v", int64(offset/time.Second))
+
+	delete(data, "notification_type")
+	for i, t := range model.NotificationTypes {
+		if t == offset {
+			data["notification_type"] = fmt.Sprintf("%v", i)
+			break
+		}
+	}
 }
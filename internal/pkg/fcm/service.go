@@ -3,6 +3,7 @@ package fcm
 import (
 	"context"
 	"fmt"
+	"time"
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/messaging"
@@ -46,37 +47,107 @@ func (s *Service) SendMessage(ctx context.Context, m *Message) error {
 	return nil
 }
 
-const batchSize = 500
+const (
+	batchSize      = 500
+	maxAttempts    = 4
+	initialBackoff = time.Second
+)
 
-func (s *Service) SendMessageBatch(ctx context.Context, ms []*Message) error {
-	messages := make([]*messaging.Message, len(ms))
-	for i, m := range ms {
-		messages[i] = &messaging.Message{
-			Data:  m.Data,
-			Token: m.Token,
-		}
-	}
+// Result is the outcome of sending a single message.
+type Result struct {
+	Err error
+	// Retryable reports that the message failed after all attempts, but can be sent later.
+	Retryable bool
+	// InvalidToken reports that the token was unregistered and must not be used anymore.
+	InvalidToken bool
+}
+
+// SendMessageBatch returns results in the order of messages. Messages failed with retryable
+// errors are sent again with exponential backoff.
+func (s *Service) SendMessageBatch(ctx context.Context, ms []*Message) ([]*Result, error) {
+	results := make([]*Result, len(ms))
 
 	g, ctx := errgroup.WithContext(ctx)
-	for i := 0; i < len(messages); i += batchSize {
+	for i := 0; i < len(ms); i += batchSize {
 		from := i
 		to := i + batchSize
-		if to > len(messages) {
-			to = len(messages)
+		if to > len(ms) {
+			to = len(ms)
 		}
 
 		g.Go(func() error {
-			_, err := s.client.SendAll(ctx, messages[from:to])
-			if err != nil {
-				return fmt.Errorf("send message: %w", err)
-			}
-			return nil
+			return s.sendBatch(ctx, ms[from:to], results[from:to])
 		})
 	}
 
 	if err := g.Wait(); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return results, nil
+}
+
+func (s *Service) sendBatch(ctx context.Context, ms []*Message, results []*Result) error {
+	pending := make([]int, len(ms))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		messages := make([]*messaging.Message, len(pending))
+		for i, idx := range pending {
+			messages[i] = &messaging.Message{
+				Data:  ms[idx].Data,
+				Token: ms[idx].Token,
+			}
+		}
+
+		// whole batch fails on transport errors, all messages are sent again then
+		resp, batchErr := s.client.SendAll(ctx, messages)
+
+		var retry []int
+		for i, idx := range pending {
+			result := &Result{}
+			switch {
+			case batchErr != nil:
+				result.Err = fmt.Errorf("send messages: %w", batchErr)
+				result.Retryable = true
+			case !resp.Responses[i].Success:
+				result.Err = fmt.Errorf("send message: %w", resp.Responses[i].Error)
+				result.Retryable = retryable(resp.Responses[i].Error)
+				result.InvalidToken = invalidToken(resp.Responses[i].Error)
+			}
+			results[idx] = result
+
+			if result.Retryable {
+				retry = append(retry, idx)
+			}
+		}
+
+		if len(retry) == 0 || attempt == maxAttempts {
+			return nil
+		}
+		pending = retry
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(err error) bool {
+	return messaging.IsServerUnavailable(err) ||
+		messaging.IsInternal(err) ||
+		messaging.IsMessageRateExceeded(err) ||
+		messaging.IsUnknown(err)
+}
+
+// invalidToken reports whether the token was unregistered. Invalid argument isn't counted,
+// as it is returned for oversized payloads as well.
+func invalidToken(err error) bool {
+	return messaging.IsRegistrationTokenNotRegistered(err)
 }
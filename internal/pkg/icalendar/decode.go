@@ -0,0 +1,491 @@
+package icalendar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+const defaultTitle = "(No title)"
+
+var frequencies = map[rrule.Frequency]model.Frequency{
+	rrule.DAILY:   model.FrequencyDaily,
+	rrule.WEEKLY:  model.FrequencyWeekly,
+	rrule.MONTHLY: model.FrequencyMonthly,
+	rrule.YEARLY:  model.FrequencyYearly,
+}
+
+// errUnsupported is returned for events that can't be represented in the planner, its message is shown in the report.
+type errUnsupported string
+
+func (e errUnsupported) Error() string {
+	return string(e)
+}
+
+type DecodeOptions struct {
+	// Location is used for floating times and all-day events.
+	Location *time.Location
+	// SupportedReminder reports whether the reminder offset can be stored, other reminders are dropped.
+	SupportedReminder func(time.Duration) bool
+}
+
+// Decode reads VEVENTs from the iCalendar stream. Occurrence overrides are attached to their series by UID.
+// Components that can't be imported are returned as report items with the reason.
+func Decode(r io.Reader, opts DecodeOptions) ([]*model.ImportedEvent, []*model.ImportItem, error) {
+	var cals []*ical.Calendar
+
+	dec := ical.NewDecoder(r)
+	for {
+		cal, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		cals = append(cals, cal)
+	}
+
+	return DecodeCalendars(cals, opts)
+}
+
+// DecodeCalendars maps VEVENTs of the decoded calendars the same way as Decode.
+func DecodeCalendars(cals []*ical.Calendar, opts DecodeOptions) ([]*model.ImportedEvent, []*model.ImportItem, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	type series struct {
+		main      *ical.Component
+		overrides []*ical.Component
+	}
+
+	var uids []string
+	seriesMap := make(map[string]*series)
+	var items []*model.ImportItem
+
+	for _, cal := range cals {
+		for _, c := range cal.Children {
+			switch c.Name {
+			case ical.CompEvent:
+			case ical.CompToDo, ical.CompJournal:
+				items = append(items, newItem(c, model.ImportStatusUnsupported, "only events can be imported"))
+				continue
+			default:
+				continue
+			}
+
+			uid, _ := c.Props.Text(ical.PropUID)
+			if uid == "" {
+				items = append(items, newItem(c, model.ImportStatusUnsupported, "event has no UID"))
+				continue
+			}
+
+			s, ok := seriesMap[uid]
+			if !ok {
+				s = &series{}
+				seriesMap[uid] = s
+				uids = append(uids, uid)
+			}
+
+			if c.Props.Get(ical.PropRecurrenceID) != nil {
+				s.overrides = append(s.overrides, c)
+				continue
+			}
+
+			if s.main != nil {
+				items = append(items, newItem(c, model.ImportStatusSkipped, "duplicate UID in the file"))
+				continue
+			}
+			s.main = c
+		}
+	}
+
+	var res []*model.ImportedEvent
+	for _, uid := range uids {
+		s := seriesMap[uid]
+		if s.main == nil {
+			items = append(items, newItem(s.overrides[0], model.ImportStatusUnsupported, "changed occurrence without its series"))
+			continue
+		}
+
+		if status, _ := s.main.Props.Text(ical.PropStatus); strings.EqualFold(status, string(ical.EventCancelled)) {
+			items = append(items, newItem(s.main, model.ImportStatusSkipped, "event is cancelled"))
+			continue
+		}
+
+		event, err := decodeSeries(uid, s.main, s.overrides, opts)
+		if err != nil {
+			var unsupported errUnsupported
+			if errors.As(err, &unsupported) {
+				items = append(items, newItem(s.main, model.ImportStatusUnsupported, unsupported.Error()))
+				continue
+			}
+			return nil, nil, fmt.Errorf("event %q: %w", uid, err)
+		}
+		event.Warnings = unique(event.Warnings)
+
+		res = append(res, event)
+	}
+
+	return res, items, nil
+}
+
+func newItem(c *ical.Component, status model.ImportStatus, reason string) *model.ImportItem {
+	uid, _ := c.Props.Text(ical.PropUID)
+	title, _ := c.Props.Text(ical.PropSummary)
+
+	return &model.ImportItem{
+		UID:    uid,
+		Title:  title,
+		Status: status,
+		Reason: reason,
+	}
+}
+
+func decodeSeries(uid string, main *ical.Component, overrides []*ical.Component, opts DecodeOptions) (*model.ImportedEvent, error) {
+	content, err := decodeContent(main, opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.ImportedEvent{
+		EventCreate: model.EventCreate{
+			EventType:   model.EventTypeEvent,
+			Title:       content.title,
+			Description: content.description,
+			AllDay:      content.allDay,
+			From:        content.from,
+			To:          content.to,
+			TimeZone:    content.loc.String(),
+			NotifyAt:    content.notifyAt,
+			Attachments: content.attachments,
+			UID:         uid,
+		},
+		Exceptions: map[int64]struct{}{},
+		Warnings:   content.warnings,
+	}
+	res.Notifications, res.Warnings = filterReminders(content.notifications, opts, res.Warnings)
+
+	if main.Props.Get(ical.PropRecurrenceDates) != nil {
+		return nil, errUnsupported("additional occurrence dates (RDATE) are not supported")
+	}
+
+	if main.Props.Get(ical.PropRecurrenceRule) == nil {
+		if len(overrides) != 0 {
+			res.Warnings = append(res.Warnings, "changed occurrences of non-repeating event were dropped")
+		}
+		return res, nil
+	}
+
+	rOption, err := main.Props.RecurrenceRule()
+	if err != nil {
+		return nil, errUnsupported("invalid repeat rule")
+	}
+
+	res.Recurrence, err = decodeRecurrence(rOption, content.from, content.allDay, content.loc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, prop := range main.Props.Values(ical.PropExceptionDates) {
+		for _, value := range strings.Split(prop.Value, ",") {
+			p := prop
+			p.Value = value
+
+			t, err := decodeTime(&p, content.loc)
+			if err != nil {
+				return nil, errUnsupported("invalid excluded date")
+			}
+			res.Exceptions[t.Unix()] = struct{}{}
+		}
+	}
+
+	for _, o := range overrides {
+		recurrenceID := o.Props.Get(ical.PropRecurrenceID)
+		if strings.EqualFold(recurrenceID.Params.Get(ical.ParamRange), "THISANDFUTURE") {
+			res.Warnings = append(res.Warnings, "changes of following occurrences were dropped")
+			continue
+		}
+
+		originalStart, err := decodeTime(recurrenceID, content.loc)
+		if err != nil {
+			res.Warnings = append(res.Warnings, "changed occurrence with invalid recurrence id was dropped")
+			continue
+		}
+
+		if status, _ := o.Props.Text(ical.PropStatus); strings.EqualFold(status, string(ical.EventCancelled)) {
+			res.Exceptions[originalStart.Unix()] = struct{}{}
+			continue
+		}
+
+		oContent, err := decodeContent(o, content.loc)
+		if err != nil {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("changed occurrence at %v was dropped: %v", originalStart.Format(time.RFC3339), err))
+			continue
+		}
+
+		override := &model.EventOverride{
+			OriginalStart: originalStart,
+			EventType:     model.EventTypeEvent,
+			Title:         oContent.title,
+			Description:   oContent.description,
+			AllDay:        oContent.allDay,
+			From:          oContent.from,
+			To:            oContent.to,
+			Attachments:   oContent.attachments,
+		}
+		override.Notifications, res.Warnings = filterReminders(oContent.notifications, opts, res.Warnings)
+		// absolute reminders fire once, so they are kept by the series
+		res.NotifyAt = append(res.NotifyAt, oContent.notifyAt...)
+		res.Warnings = append(res.Warnings, oContent.warnings...)
+
+		res.Overrides = append(res.Overrides, override)
+	}
+
+	return res, nil
+}
+
+type content struct {
+	title         string
+	description   string
+	allDay        bool
+	from          time.Time
+	to            time.Time
+	loc           *time.Location
+	notifications []time.Duration
+	notifyAt      []time.Time
+	attachments   []*model.Attachment
+	warnings      []string
+}
+
+// decodeContent reads the fields shared by series and their overrides. Time zone of the event
+// is taken from DTSTART, floating and UTC times use loc.
+func decodeContent(c *ical.Component, loc *time.Location) (*content, error) {
+	res := &content{
+		title: defaultTitle,
+		loc:   loc,
+	}
+
+	if title, _ := c.Props.Text(ical.PropSummary); title != "" {
+		res.title = title
+	}
+	res.description, _ = c.Props.Text(ical.PropDescription)
+
+	start := c.Props.Get(ical.PropDateTimeStart)
+	if start == nil {
+		return nil, errUnsupported("event has no start")
+	}
+
+	if tzid := start.Params.Get(ical.PropTimezoneID); tzid != "" {
+		tzLoc, err := time.LoadLocation(tzid)
+		if err != nil {
+			return nil, errUnsupported(fmt.Sprintf("unknown time zone %q", tzid))
+		}
+		res.loc = tzLoc
+	}
+
+	var err error
+	res.allDay = start.ValueType() == ical.ValueDate
+	res.from, err = decodeTime(start, res.loc)
+	if err != nil {
+		return nil, errUnsupported("invalid event start")
+	}
+
+	switch {
+	case c.Props.Get(ical.PropDateTimeEnd) != nil:
+		res.to, err = decodeTime(c.Props.Get(ical.PropDateTimeEnd), res.loc)
+		if err != nil {
+			return nil, errUnsupported("invalid event end")
+		}
+	case c.Props.Get(ical.PropDuration) != nil:
+		d, err := c.Props.Get(ical.PropDuration).Duration()
+		if err != nil {
+			return nil, errUnsupported("invalid event duration")
+		}
+		if res.allDay && d%(24*time.Hour) == 0 {
+			res.to = res.from.AddDate(0, 0, int(d/(24*time.Hour)))
+		} else {
+			res.to = res.from.Add(d)
+		}
+	case res.allDay:
+		res.to = res.from.AddDate(0, 0, 1)
+	default:
+		res.to = res.from
+	}
+
+	if res.to.Before(res.from) {
+		return nil, errUnsupported("event ends before it starts")
+	}
+
+	for _, alarm := range c.Children {
+		if alarm.Name != ical.CompAlarm {
+			continue
+		}
+
+		trigger := alarm.Props.Get(ical.PropTrigger)
+		if trigger != nil && trigger.ValueType() == ical.ValueDateTime {
+			t, err := trigger.DateTime(time.UTC)
+			if err != nil {
+				res.warnings = append(res.warnings, "reminders with invalid time were dropped")
+				continue
+			}
+
+			res.notifyAt = append(res.notifyAt, t)
+			continue
+		}
+
+		if trigger == nil ||
+			trigger.ValueType() != ical.ValueDuration ||
+			strings.EqualFold(trigger.Params.Get(ical.ParamRelated), "END") {
+			res.warnings = append(res.warnings, "reminders relative to the end were dropped")
+			continue
+		}
+
+		d, err := trigger.Duration()
+		if err != nil || d > 0 {
+			res.warnings = append(res.warnings, "reminders after the event start were dropped")
+			continue
+		}
+
+		res.notifications = append(res.notifications, -d)
+	}
+
+	for _, prop := range c.Props.Values(ical.PropAttach) {
+		if prop.ValueType() == ical.ValueBinary {
+			res.warnings = append(res.warnings, "embedded attachments were dropped")
+			continue
+		}
+
+		res.attachments = append(res.attachments, &model.Attachment{
+			Name: prop.Params.Get("FILENAME"),
+			Path: prop.Value,
+		})
+	}
+
+	return res, nil
+}
+
+// decodeTime parses date or date-time value, dates are midnights in loc.
+func decodeTime(prop *ical.Prop, loc *time.Location) (time.Time, error) {
+	if prop.Params.Get(ical.PropTimezoneID) != "" {
+		return prop.DateTime(loc)
+	}
+
+	// VALUE parameter is often omitted for dates
+	if len(prop.Value) == len("20060102") {
+		return time.ParseInLocation("20060102", prop.Value, loc)
+	}
+
+	return prop.DateTime(loc)
+}
+
+// decodeRecurrence maps RRULE to the structured recurrence, rules that can't be expressed by it are unsupported.
+func decodeRecurrence(rOption *rrule.ROption, from time.Time, allDay bool, loc *time.Location) (*model.Recurrence, error) {
+	freq, ok := frequencies[rOption.Freq]
+	if !ok {
+		return nil, errUnsupported("events repeating more often than daily are not supported")
+	}
+
+	if len(rOption.Byhour) != 0 || len(rOption.Byminute) != 0 || len(rOption.Bysecond) != 0 ||
+		len(rOption.Byweekno) != 0 || len(rOption.Byyearday) != 0 || len(rOption.Byeaster) != 0 {
+		return nil, errUnsupported("repeat rule is too complex")
+	}
+
+	if rOption.Count != 0 && !rOption.Until.IsZero() {
+		return nil, errUnsupported("invalid repeat rule")
+	}
+
+	res := &model.Recurrence{
+		Frequency:  freq,
+		Interval:   rOption.Interval,
+		ByMonthDay: rOption.Bymonthday,
+		BySetPos:   rOption.Bysetpos,
+		Count:      rOption.Count,
+	}
+	if res.Interval == 0 {
+		res.Interval = 1
+	}
+
+	for _, d := range rOption.Byweekday {
+		res.ByDay = append(res.ByDay, model.WeekdayNum{
+			Weekday: time.Weekday((d.Day() + 1) % 7),
+			N:       d.N(),
+		})
+	}
+
+	if len(rOption.Bymonth) != 0 {
+		// yearly rule in the month of the start is the only BYMONTH case that can be expressed,
+		// with BYDAY or BYMONTHDAY it becomes a monthly rule repeating every 12 months
+		if freq != model.FrequencyYearly || len(rOption.Bymonth) != 1 || time.Month(rOption.Bymonth[0]) != from.In(loc).Month() {
+			return nil, errUnsupported("repeat rule is too complex")
+		}
+
+		if len(res.ByDay) != 0 || len(res.ByMonthDay) != 0 {
+			res.Frequency = model.FrequencyMonthly
+			res.Interval *= 12
+		}
+	}
+
+	for _, d := range res.ByDay {
+		if d.N != 0 && res.Frequency != model.FrequencyMonthly && res.Frequency != model.FrequencyYearly {
+			return nil, errUnsupported("invalid repeat rule")
+		}
+	}
+	if len(res.ByMonthDay) != 0 && res.Frequency == model.FrequencyWeekly {
+		return nil, errUnsupported("invalid repeat rule")
+	}
+
+	if !rOption.Until.IsZero() {
+		until := rOption.Until
+		if allDay {
+			// date UNTIL is parsed as UTC midnight, while occurrences start at midnight in loc
+			y, m, d := until.UTC().Date()
+			until = time.Date(y, m, d, 23, 59, 59, 0, loc)
+		}
+		if until.Before(from) {
+			return nil, errUnsupported("invalid repeat rule")
+		}
+		res.Until = &until
+	}
+
+	return res, nil
+}
+
+func filterReminders(notifications []time.Duration, opts DecodeOptions, warnings []string) ([]time.Duration, []string) {
+	var res []time.Duration
+	dropped := false
+	for _, n := range notifications {
+		if opts.SupportedReminder != nil && !opts.SupportedReminder(n) {
+			dropped = true
+			continue
+		}
+		res = append(res, n)
+	}
+
+	if dropped {
+		warnings = append(warnings, "reminders with unsupported offsets were dropped")
+	}
+
+	return res, warnings
+}
+
+func unique(values []string) []string {
+	var res []string
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		res = append(res, v)
+	}
+
+	return res
+}
@@ -0,0 +1,239 @@
+package icalendar
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	productID = "-//Shared Planner//Shared Planner//EN"
+	uidSuffix = "@shared-planner"
+)
+
+// Encode writes series as RFC 5545 VCALENDAR. Relative attachment paths are resolved against baseURL.
+func Encode(w io.Writer, name string, series []*model.Series, baseURL string) error {
+	cal := newCalendar()
+
+	calName := ical.NewProp("X-WR-CALNAME")
+	calName.SetText(name)
+	calName.Params.Del(ical.ParamValue)
+	cal.Props.Set(calName)
+
+	cal.Children = timeZoneComponents(series)
+
+	for _, s := range series {
+		components, err := SeriesToComponents(s, baseURL)
+		if err != nil {
+			return fmt.Errorf("series %v: %w", s.Event.ID, err)
+		}
+		cal.Children = append(cal.Children, components...)
+	}
+
+	// encoder refuses calendars without components, but an empty feed is still valid for clients
+	if len(cal.Children) == 0 {
+		_, err := io.WriteString(w, strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:" + productID,
+			"END:VCALENDAR",
+			"",
+		}, "\r\n"))
+		return err
+	}
+
+	return ical.NewEncoder(w).Encode(cal)
+}
+
+// SeriesToCalendar maps the series to a separate VCALENDAR, as CalDAV stores each series in its own resource.
+func SeriesToCalendar(s *model.Series, baseURL string) (*ical.Calendar, error) {
+	components, err := SeriesToComponents(s, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := newCalendar()
+	cal.Children = append(timeZoneComponents([]*model.Series{s}), components...)
+
+	return cal, nil
+}
+
+func newCalendar() *ical.Calendar {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropProductID, productID)
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropCalendarScale, "GREGORIAN")
+
+	return cal
+}
+
+// UID returns the iCalendar UID of the series, all of its overrides share the same UID.
+// Imported events keep their original UID.
+func UID(e *model.Event) string {
+	if e.UID != "" {
+		return e.UID
+	}
+	return fmt.Sprintf("%s%s", e.ID, uidSuffix)
+}
+
+// ParseUID returns id of the event created in the app by its UID.
+func ParseUID(uid string) (int64, bool) {
+	if !strings.HasSuffix(uid, uidSuffix) {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSuffix(uid, uidSuffix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// SeriesToComponents maps the series to VEVENT, overrides are mapped to separate VEVENTs with RECURRENCE-ID.
+func SeriesToComponents(s *model.Series, baseURL string) ([]*ical.Component, error) {
+	e := s.Event
+
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	uid := UID(e)
+
+	main := newEvent(uid, e.UpdatedAt)
+	main.Props.SetDateTime(ical.PropCreated, e.CreatedAt.UTC())
+	main.Props.SetDateTime(ical.PropLastModified, e.UpdatedAt.UTC())
+	setContent(main, e.Title, e.Description, e.AllDay, e.From, e.To, loc, e.Notifications, e.Attachments, baseURL)
+	for _, t := range e.NotifyAt {
+		trigger := ical.NewProp(ical.PropTrigger)
+		trigger.SetDateTime(t.UTC())
+		main.Children = append(main.Children, newAlarm(e.Title, trigger))
+	}
+
+	if e.RepeatRule != "" {
+		rOption, err := rrule.StrToROption(e.RepeatRule)
+		if err != nil {
+			return nil, fmt.Errorf("parse repeat rule %q: %w", e.RepeatRule, err)
+		}
+		main.Props.SetRecurrenceRule(rOption)
+
+		for ts := range e.Exceptions {
+			main.Props.Add(dateProp(ical.PropExceptionDates, time.Unix(ts, 0), e.AllDay, loc))
+		}
+	}
+
+	res := []*ical.Component{main.Component}
+
+	for _, o := range s.Overrides {
+		override := newEvent(uid, o.UpdatedAt)
+		override.Props.SetDateTime(ical.PropLastModified, o.UpdatedAt.UTC())
+		override.Props.Set(dateProp(ical.PropRecurrenceID, o.OriginalStart, e.AllDay, loc))
+		setContent(override, o.Title, o.Description, o.AllDay, o.From, o.To, loc, o.Notifications, o.Attachments, baseURL)
+
+		res = append(res, override.Component)
+	}
+
+	return res, nil
+}
+
+func newEvent(uid string, stamp time.Time) *ical.Event {
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, uid)
+	event.Props.SetDateTime(ical.PropDateTimeStamp, stamp.UTC())
+
+	return event
+}
+
+func setContent(
+	event *ical.Event,
+	title string,
+	description string,
+	allDay bool,
+	from time.Time,
+	to time.Time,
+	loc *time.Location,
+	notifications []time.Duration,
+	attachments []*model.Attachment,
+	baseURL string,
+) {
+	event.Props.SetText(ical.PropSummary, title)
+	if description != "" {
+		event.Props.SetText(ical.PropDescription, description)
+	}
+
+	event.Props.Set(dateProp(ical.PropDateTimeStart, from, allDay, loc))
+	if allDay {
+		event.Props.Set(dateProp(ical.PropDateTimeEnd, allDayEnd(from, to, loc), true, loc))
+	} else if to.After(from) {
+		event.Props.Set(dateProp(ical.PropDateTimeEnd, to, false, loc))
+	}
+
+	for _, n := range notifications {
+		trigger := ical.NewProp(ical.PropTrigger)
+		trigger.SetDuration(-n)
+		event.Children = append(event.Children, newAlarm(title, trigger))
+	}
+
+	for _, a := range attachments {
+		attach := ical.NewProp(ical.PropAttach)
+		attach.SetValueType(ical.ValueURI)
+		attach.Value = resolveURL(baseURL, a.Path)
+		if a.Name != "" {
+			attach.Params.Set("FILENAME", a.Name)
+		}
+		event.Props.Add(attach)
+	}
+}
+
+func newAlarm(title string, trigger *ical.Prop) *ical.Component {
+	alarm := ical.NewComponent(ical.CompAlarm)
+	alarm.Props.SetText(ical.PropAction, "DISPLAY")
+	alarm.Props.SetText(ical.PropDescription, title)
+	alarm.Props.Set(trigger)
+
+	return alarm
+}
+
+// dateProp keeps wall-clock time of the event time zone, so that clients expand series correctly across DST changes.
+// The time zone is described by VTIMEZONE of the calendar.
+func dateProp(name string, t time.Time, allDay bool, loc *time.Location) *ical.Prop {
+	prop := ical.NewProp(name)
+	if allDay {
+		prop.SetDate(t.In(loc))
+	} else {
+		prop.SetDateTime(t.In(loc))
+	}
+
+	return prop
+}
+
+// allDayEnd returns the exclusive end date of the all-day event.
+func allDayEnd(from, to time.Time, loc *time.Location) time.Time {
+	from = from.In(loc)
+	to = to.In(loc)
+
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, loc)
+	if !to.Equal(end) || !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return end
+}
+
+func resolveURL(baseURL, path string) string {
+	u, err := url.Parse(path)
+	if err != nil || u.IsAbs() || baseURL == "" {
+		return path
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
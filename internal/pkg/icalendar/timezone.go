@@ -0,0 +1,296 @@
+package icalendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/SergeyKozhin/shared-planner-backend/internal/model"
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+const localDateTimeFormat = "20060102T150405"
+
+type tzTransition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	dst        bool
+}
+
+type tzRange struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// timeZoneComponents returns VTIMEZONE of every time zone referenced by TZID of the series,
+// clients don't have to know IANA names to resolve them.
+func timeZoneComponents(series []*model.Series) []*ical.Component {
+	ranges := make(map[string]*tzRange)
+	for _, s := range series {
+		loc, err := time.LoadLocation(s.Event.TimeZone)
+		if err != nil || loc == time.UTC || !hasTimedDates(s) {
+			continue
+		}
+
+		r, ok := ranges[loc.String()]
+		if !ok {
+			r = &tzRange{loc: loc, from: s.Event.From, to: s.Event.From}
+			ranges[loc.String()] = r
+		}
+
+		for _, t := range seriesTimes(s) {
+			if t.Before(r.from) {
+				r.from = t
+			}
+			if t.After(r.to) {
+				r.to = t
+			}
+		}
+	}
+
+	names := make([]string, 0, len(ranges))
+	for name := range ranges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// series can go on after their stored dates, so transitions up to now are listed at least
+	now := time.Now()
+
+	res := make([]*ical.Component, len(names))
+	for i, name := range names {
+		r := ranges[name]
+		if r.to.Before(now) {
+			r.to = now
+		}
+		res[i] = newTimeZone(r.loc, r.from, r.to)
+	}
+
+	return res
+}
+
+// hasTimedDates reports whether any date of the series is written as DATE-TIME with TZID,
+// RECURRENCE-ID and EXDATE follow the main event.
+func hasTimedDates(s *model.Series) bool {
+	if !s.Event.AllDay {
+		return true
+	}
+
+	for _, o := range s.Overrides {
+		if !o.AllDay {
+			return true
+		}
+	}
+
+	return false
+}
+
+func seriesTimes(s *model.Series) []time.Time {
+	res := []time.Time{s.Event.From, s.Event.To}
+	for ts := range s.Event.Exceptions {
+		res = append(res, time.Unix(ts, 0))
+	}
+	for _, o := range s.Overrides {
+		res = append(res, o.From, o.To, o.OriginalStart)
+	}
+
+	return res
+}
+
+// ruleCheckYears is how many years after the listed ones the yearly rule is checked against the time zone database.
+const ruleCheckYears = 10
+
+// newTimeZone lists transitions of loc from the year of from to the year of to. Transitions of the last year
+// repeat yearly, if they switch daylight saving time and the time zone database has the same transitions
+// in the following years, otherwise transitions of these years are listed as well.
+func newTimeZone(loc *time.Location, from, to time.Time) *ical.Component {
+	from = time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	to = time.Date(to.In(loc).Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	tz := ical.NewComponent(ical.CompTimezone)
+	tz.Props.SetText(ical.PropTimezoneID, loc.String())
+
+	transitions := zoneTransitions(loc, from, to.AddDate(ruleCheckYears, 0, 0))
+	if len(transitions) == 0 {
+		name, offset := from.Zone()
+		tz.Children = append(tz.Children, newObservance(tzTransition{
+			at:         from,
+			offsetFrom: offset,
+			offsetTo:   offset,
+			name:       name,
+			dst:        from.IsDST(),
+		}, nil))
+		return tz
+	}
+
+	listed := 0
+	for listed < len(transitions) && transitions[listed].at.Before(to) {
+		listed++
+	}
+
+	// daylight saving time is switched on and off in the last year
+	repeated := false
+	if listed > 1 {
+		prev, last := transitions[listed-2], transitions[listed-1]
+		repeated = prev.at.Year() == last.at.Year() && last.at.Year() == to.Year()-1 && prev.dst != last.dst &&
+			rulesMatch(transitions[listed-2:listed], transitions[listed:], to, ruleCheckYears)
+	}
+
+	if !repeated {
+		listed = len(transitions)
+	}
+
+	for i, t := range transitions[:listed] {
+		var rule *rrule.ROption
+		if repeated && i >= listed-2 {
+			rule = yearlyRule(t)
+		}
+		tz.Children = append(tz.Children, newObservance(t, rule))
+	}
+
+	return tz
+}
+
+// rulesMatch reports whether yearly rules of the transitions give exactly the following transitions
+// during the given number of years since from.
+func rulesMatch(transitions, following []tzTransition, from time.Time, years int) bool {
+	type key struct {
+		local                time.Time
+		offsetFrom, offsetTo int
+	}
+
+	want := make(map[key]struct{}, len(following))
+	for _, t := range following {
+		want[key{localTime(t), t.offsetFrom, t.offsetTo}] = struct{}{}
+	}
+
+	start := time.Date(from.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(years, 0, 0)
+
+	got := 0
+	for _, t := range transitions {
+		option := yearlyRule(t)
+		option.Dtstart = localTime(t)
+
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return false
+		}
+
+		for _, local := range rule.Between(start, end, true) {
+			if _, ok := want[key{local, t.offsetFrom, t.offsetTo}]; !ok {
+				return false
+			}
+			got++
+		}
+	}
+
+	return got == len(following)
+}
+
+func newObservance(t tzTransition, rule *rrule.ROption) *ical.Component {
+	name := ical.CompTimezoneStandard
+	if t.dst {
+		name = ical.CompTimezoneDaylight
+	}
+
+	observance := ical.NewComponent(name)
+
+	// onset is written in local time before the transition
+	start := ical.NewProp(ical.PropDateTimeStart)
+	start.Value = localTime(t).Format(localDateTimeFormat)
+	observance.Props.Set(start)
+
+	offsetFrom := ical.NewProp(ical.PropTimezoneOffsetFrom)
+	offsetFrom.Value = formatOffset(t.offsetFrom)
+	observance.Props.Set(offsetFrom)
+
+	offsetTo := ical.NewProp(ical.PropTimezoneOffsetTo)
+	offsetTo.Value = formatOffset(t.offsetTo)
+	observance.Props.Set(offsetTo)
+
+	if t.name != "" {
+		observance.Props.SetText(ical.PropTimezoneName, t.name)
+	}
+	if rule != nil {
+		observance.Props.SetRecurrenceRule(rule)
+	}
+
+	return observance
+}
+
+// yearlyRule repeats the transition on the same weekday of the month, the last one if it is in the last week.
+func yearlyRule(t tzTransition) *rrule.ROption {
+	local := localTime(t)
+	daysInMonth := time.Date(local.Year(), local.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	n := (local.Day()-1)/7 + 1
+	if local.Day()+7 > daysInMonth {
+		n = -1
+	}
+
+	weekdays := []rrule.Weekday{rrule.SU, rrule.MO, rrule.TU, rrule.WE, rrule.TH, rrule.FR, rrule.SA}
+
+	return &rrule.ROption{
+		Freq:      rrule.YEARLY,
+		Bymonth:   []int{int(local.Month())},
+		Byweekday: []rrule.Weekday{weekdays[local.Weekday()].Nth(n)},
+	}
+}
+
+func localTime(t tzTransition) time.Time {
+	return t.at.UTC().Add(time.Duration(t.offsetFrom) * time.Second)
+}
+
+// zoneTransitions finds changes of the offset day by day, the exact moment is found by binary search.
+func zoneTransitions(loc *time.Location, from, to time.Time) []tzTransition {
+	var res []tzTransition
+
+	_, offset := from.In(loc).Zone()
+	for day := from; day.Before(to); {
+		next := day.Add(24 * time.Hour)
+		if _, nextOffset := next.In(loc).Zone(); nextOffset != offset {
+			lo, hi := day, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.In(loc).Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+
+			at := hi.Truncate(time.Second).In(loc)
+			name, newOffset := at.Zone()
+			res = append(res, tzTransition{
+				at:         at,
+				offsetFrom: offset,
+				offsetTo:   newOffset,
+				name:       name,
+				dst:        at.IsDST(),
+			})
+			offset = newOffset
+		}
+		day = next
+	}
+
+	return res
+}
+
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+
+	res := fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+	if s := offset % 60; s != 0 {
+		res += fmt.Sprintf("%02d", s)
+	}
+
+	return res
+}
@@ -0,0 +1,142 @@
+package icalendar
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/emersion/go-ical"
+)
+
+type observance struct {
+	name       string
+	start      string
+	offsetFrom string
+	offsetTo   string
+	rule       string
+}
+
+func TestNewTimeZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     string
+		fromYear int
+		toYear   int
+		want     []observance
+	}{
+		{
+			name:     "northern dst",
+			zone:     "America/New_York",
+			fromYear: 2024,
+			toYear:   2024,
+			want: []observance{
+				{ical.CompTimezoneDaylight, "20240310T020000", "-0500", "-0400", "FREQ=YEARLY;BYMONTH=3;BYDAY=+2SU"},
+				{ical.CompTimezoneStandard, "20241103T020000", "-0400", "-0500", "FREQ=YEARLY;BYMONTH=11;BYDAY=+1SU"},
+			},
+		},
+		{
+			name:     "rules changed during the range",
+			zone:     "America/New_York",
+			fromYear: 2006,
+			toYear:   2007,
+			want: []observance{
+				{ical.CompTimezoneDaylight, "20060402T020000", "-0500", "-0400", ""},
+				{ical.CompTimezoneStandard, "20061029T020000", "-0400", "-0500", ""},
+				{ical.CompTimezoneDaylight, "20070311T020000", "-0500", "-0400", "FREQ=YEARLY;BYMONTH=3;BYDAY=+2SU"},
+				{ical.CompTimezoneStandard, "20071104T020000", "-0400", "-0500", "FREQ=YEARLY;BYMONTH=11;BYDAY=+1SU"},
+			},
+		},
+		{
+			name:     "last weekday of the month",
+			zone:     "Europe/London",
+			fromYear: 2024,
+			toYear:   2024,
+			want: []observance{
+				{ical.CompTimezoneDaylight, "20240331T010000", "+0000", "+0100", "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU"},
+				{ical.CompTimezoneStandard, "20241027T020000", "+0100", "+0000", "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU"},
+			},
+		},
+		{
+			name:     "southern dst",
+			zone:     "Australia/Sydney",
+			fromYear: 2024,
+			toYear:   2024,
+			want: []observance{
+				{ical.CompTimezoneStandard, "20240407T030000", "+1100", "+1000", "FREQ=YEARLY;BYMONTH=4;BYDAY=+1SU"},
+				{ical.CompTimezoneDaylight, "20241006T020000", "+1000", "+1100", "FREQ=YEARLY;BYMONTH=10;BYDAY=+1SU"},
+			},
+		},
+		{
+			name:     "dst stopped after the range",
+			zone:     "Asia/Tehran",
+			fromYear: 2021,
+			toYear:   2021,
+			want: []observance{
+				{ical.CompTimezoneDaylight, "20210322T000000", "+0330", "+0430", ""},
+				{ical.CompTimezoneStandard, "20210922T000000", "+0430", "+0330", ""},
+				{ical.CompTimezoneDaylight, "20220322T000000", "+0330", "+0430", ""},
+				{ical.CompTimezoneStandard, "20220922T000000", "+0430", "+0330", ""},
+			},
+		},
+		{
+			name:     "dst stopped before the range",
+			zone:     "Asia/Tehran",
+			fromYear: 2024,
+			toYear:   2024,
+			want: []observance{
+				{ical.CompTimezoneStandard, "20240101T000000", "+0330", "+0330", ""},
+			},
+		},
+		{
+			name:     "no dst",
+			zone:     "Asia/Tokyo",
+			fromYear: 2024,
+			toYear:   2025,
+			want: []observance{
+				{ical.CompTimezoneStandard, "20240101T000000", "+0900", "+0900", ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatalf("load location: %v", err)
+			}
+
+			tz := newTimeZone(loc, time.Date(tt.fromYear, time.June, 1, 0, 0, 0, 0, loc), time.Date(tt.toYear, time.June, 1, 0, 0, 0, 0, loc))
+
+			if id, _ := tz.Props.Text(ical.PropTimezoneID); id != tt.zone {
+				t.Errorf("TZID = %q, want %q", id, tt.zone)
+			}
+
+			got := make([]observance, len(tz.Children))
+			for i, c := range tz.Children {
+				got[i] = observance{
+					name:       c.Name,
+					start:      propValue(c, ical.PropDateTimeStart),
+					offsetFrom: propValue(c, ical.PropTimezoneOffsetFrom),
+					offsetTo:   propValue(c, ical.PropTimezoneOffsetTo),
+					rule:       propValue(c, ical.PropRecurrenceRule),
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d observances %+v, want %d %+v", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("observance %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func propValue(c *ical.Component, name string) string {
+	if p := c.Props.Get(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type Service struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewService creates the SMTP sender, authentication is skipped if username is empty.
+func NewService(host string, port int, username, password, from string) *Service {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &Service{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send sends the plain text email. SMTP errors are returned as *textproto.Error,
+// so that the caller can tell temporary failures by their 4xx codes.
+func (s *Service) Send(ctx context.Context, to, subject, body string) error {
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(msg, "To: %s\r\n", to)
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(msg, "\r\n%s\r\n", body)
+
+	// net/smtp doesn't accept context, so the message is sent in background and abandoned on cancellation
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(s.addr, s.auth, s.from, []string{to}, msg.Bytes())
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("send mail: %w", err)
+		}
+		return nil
+	}
+}
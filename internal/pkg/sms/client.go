@@ -0,0 +1,75 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Client sends messages through the HTTP gateway, that accepts JSON with from, to and text fields.
+type Client struct {
+	client *http.Client
+	url    string
+	token  string
+	from   string
+}
+
+func NewClient(url, token, from string, timeout time.Duration) *Client {
+	return &Client{
+		client: &http.Client{Timeout: timeout},
+		url:    url,
+		token:  token,
+		from:   from,
+	}
+}
+
+// StatusError is returned for non 2xx responses of the gateway.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.Code)
+}
+
+func (c *Client) Send(ctx context.Context, to, text string) error {
+	body, err := json.Marshal(&struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Text string `json:"text"`
+	}{
+		From: c.from,
+		To:   to,
+		Text: text,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Code: resp.StatusCode}
+	}
+
+	return nil
+}
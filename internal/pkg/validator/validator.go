@@ -45,3 +45,12 @@ func Unique(vals []int64) bool {
 	}
 	return true
 }
+
+func In(value string, list ...string) bool {
+	for _, v := range list {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
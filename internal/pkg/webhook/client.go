@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// SignatureHeader contains HMAC-SHA256 of the body, so that receivers can check the sender.
+const SignatureHeader = "X-Signature-256"
+
+// ErrForbiddenAddress is returned when the URL resolves to an address of the internal network.
+var ErrForbiddenAddress = errors.New("address is not public")
+
+type Client struct {
+	client *http.Client
+	secret []byte
+}
+
+// NewClient returns the client, which connects only to public addresses. The address is checked
+// on dial, after it is resolved, so that DNS can't point the checked host to the internal network.
+func NewClient(secret string, timeout time.Duration) *Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return ErrForbiddenAddress
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// the proxy would connect on behalf of the client bypassing the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Client{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		secret: []byte(secret),
+	}
+}
+
+// PublicIP reports whether the ip is not a loopback, private, link-local, multicast or unspecified address.
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// StatusError is returned for non 2xx responses.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.Code)
+}
+
+// Post sends the payload as JSON, redirects are not followed.
+func (c *Client) Post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Code: resp.StatusCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+begin;
+
+alter table users drop column if exists time_zone;
+alter table events drop column if exists time_zone;
+
+commit;
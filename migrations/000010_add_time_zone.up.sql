@@ -0,0 +1,6 @@
+begin;
+
+alter table users add column if not exists time_zone text not null default 'UTC';
+alter table events add column if not exists time_zone text not null default 'UTC';
+
+commit;
@@ -0,0 +1,17 @@
+create table if not exists event_overrides
+(
+    id             bigserial primary key,
+    event_id       bigint      not null references events (id) on delete cascade,
+    original_start timestamptz not null,
+    type           int         not null,
+    title          text        not null,
+    description    text        not null,
+    attachments    jsonb,
+    notifications  bigint[],
+    all_day        bool        not null,
+    start_date     timestamptz not null,
+    duration       interval,
+    unique (event_id, original_start)
+);
+
+create index if not exists event_overrides_start_date on event_overrides (start_date);
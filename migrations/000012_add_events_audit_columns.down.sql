@@ -0,0 +1,13 @@
+begin;
+
+alter table event_overrides drop column if exists updated_by;
+alter table event_overrides drop column if exists updated_at;
+
+drop index if exists events_creator_id;
+
+alter table events drop column if exists creator_id;
+alter table events drop column if exists updated_by;
+alter table events drop column if exists created_at;
+alter table events drop column if exists updated_at;
+
+commit;
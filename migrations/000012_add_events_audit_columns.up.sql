@@ -0,0 +1,29 @@
+begin;
+
+alter table events add column if not exists creator_id bigint references users (id);
+alter table events add column if not exists updated_by bigint references users (id);
+alter table events add column if not exists created_at timestamptz not null default now();
+alter table events add column if not exists updated_at timestamptz not null default now();
+
+update events e
+set creator_id = g.creator_id,
+    updated_by = g.creator_id
+from groups g
+where g.id = e.group_id;
+
+alter table events alter column creator_id set not null;
+alter table events alter column updated_by set not null;
+
+create index if not exists events_creator_id on events (creator_id);
+
+alter table event_overrides add column if not exists updated_by bigint references users (id);
+alter table event_overrides add column if not exists updated_at timestamptz not null default now();
+
+update event_overrides o
+set updated_by = e.updated_by
+from events e
+where e.id = o.event_id;
+
+alter table event_overrides alter column updated_by set not null;
+
+commit;
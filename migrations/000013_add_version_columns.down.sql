@@ -0,0 +1,6 @@
+begin;
+
+alter table events drop column if exists version;
+alter table groups drop column if exists version;
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+alter table events add column if not exists version bigint not null default 1;
+alter table groups add column if not exists version bigint not null default 1;
+
+commit;
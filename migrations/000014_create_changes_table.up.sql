@@ -0,0 +1,17 @@
+begin;
+
+-- tx_id is used instead of id for sync, since ids of concurrent transactions are committed out of order
+create table if not exists changes
+(
+    id          bigserial primary key,
+    entity_type smallint    not null,
+    entity_id   bigint      not null,
+    group_id    bigint      not null,
+    user_id     bigint,
+    tx_id       bigint      not null default pg_current_xact_id()::text::bigint,
+    created_at  timestamptz not null default now()
+);
+
+create index if not exists changes_tx_id on changes (tx_id);
+
+commit;
@@ -0,0 +1,15 @@
+begin;
+
+-- feed without group_id contains events of all user groups
+create table if not exists feed_tokens
+(
+    id         bigserial primary key,
+    token      text        not null unique,
+    user_id    bigint      not null references users (id),
+    group_id   bigint references groups (id) on delete cascade,
+    created_at timestamptz not null default now()
+);
+
+create unique index if not exists feed_tokens_user_id_group_id on feed_tokens (user_id, coalesce(group_id, 0));
+
+commit;
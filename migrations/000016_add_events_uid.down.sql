@@ -0,0 +1,7 @@
+begin;
+
+drop index if exists events_group_id_uid;
+
+alter table events drop column if exists uid;
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+alter table events add column if not exists uid text;
+
+create unique index if not exists events_group_id_uid on events (group_id, uid) where uid is not null;
+
+commit;
@@ -0,0 +1,10 @@
+-- app passwords authenticate DAV clients, that can't use Google sign-in
+create table if not exists app_passwords
+(
+    id           bigserial primary key,
+    user_id      bigint      not null references users (id),
+    name         text        not null,
+    hash         text        not null unique,
+    created_at   timestamptz not null default now(),
+    last_used_at timestamptz
+);
@@ -0,0 +1 @@
+alter table events add column if not exists notify_at timestamptz[];
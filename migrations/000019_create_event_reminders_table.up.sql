@@ -0,0 +1,12 @@
+-- personal reminders of the member replace the event defaults only when custom is set
+create table if not exists event_reminders
+(
+    user_id       bigint      not null references users (id) on delete cascade,
+    event_id      bigint      not null references events (id) on delete cascade,
+    custom        boolean     not null default false,
+    notifications bigint[],
+    notify_at     timestamptz[],
+    muted         boolean     not null default false,
+    updated_at    timestamptz not null default now(),
+    primary key (user_id, event_id)
+);
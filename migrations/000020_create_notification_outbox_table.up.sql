@@ -0,0 +1,24 @@
+begin;
+
+-- occurrence and notify_offset are null for reminders at the absolute time
+create table if not exists notification_outbox
+(
+    id            bigserial primary key,
+    user_id       bigint      not null references users (id) on delete cascade,
+    event_id      bigint      not null references events (id) on delete cascade,
+    occurrence    timestamptz,
+    notify_offset bigint,
+    send_at       timestamptz not null,
+    data          jsonb       not null,
+    status        smallint    not null default 0,
+    claimed_at    timestamptz,
+    sent_at       timestamptz,
+    created_at    timestamptz not null default now()
+);
+
+create unique index if not exists notification_outbox_key
+    on notification_outbox (event_id, coalesce(occurrence, send_at), coalesce(notify_offset, -1), user_id);
+
+create index if not exists notification_outbox_status_send_at on notification_outbox (status, send_at);
+
+commit;
@@ -0,0 +1,15 @@
+-- device is bound to the refresh token session by its hash, logout removes the device
+create table if not exists devices
+(
+    id           bigserial primary key,
+    user_id      bigint      not null references users (id) on delete cascade,
+    session_hash text        not null unique,
+    push_token   text        not null,
+    platform     text        not null,
+    app_version  text        not null default '',
+    created_at   timestamptz not null default now(),
+    last_seen_at timestamptz not null default now()
+);
+
+create index if not exists devices_user_id on devices (user_id);
+create index if not exists devices_push_token on devices (push_token);
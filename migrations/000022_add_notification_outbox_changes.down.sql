@@ -0,0 +1,12 @@
+begin;
+
+drop index if exists notification_outbox_pending_change;
+
+delete from notification_outbox where event_id is null;
+
+alter table notification_outbox
+    drop column if exists kind,
+    drop column if exists group_id,
+    alter column event_id set not null;
+
+commit;
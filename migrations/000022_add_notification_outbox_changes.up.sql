@@ -0,0 +1,13 @@
+begin;
+
+-- change notifications are not bound to an event, the event could have been deleted
+alter table notification_outbox
+    add column if not exists kind     smallint not null default 0,
+    add column if not exists group_id bigint references groups (id) on delete cascade,
+    alter column event_id drop not null;
+
+-- pending change notification of the group accumulates quick edits until it is sent
+create unique index if not exists notification_outbox_pending_change
+    on notification_outbox (user_id, group_id) where kind = 1 and status = 0;
+
+commit;
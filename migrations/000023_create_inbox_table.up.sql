@@ -0,0 +1,21 @@
+begin;
+
+-- notification_id keeps the item unique, outbox entries are deleted earlier than inbox items
+create table if not exists inbox
+(
+    id              bigserial primary key,
+    notification_id bigint      not null unique,
+    user_id         bigint      not null references users (id) on delete cascade,
+    kind            smallint    not null,
+    group_id        bigint references groups (id) on delete cascade,
+    event_id        text        not null default '',
+    data            jsonb       not null,
+    read_at         timestamptz,
+    created_at      timestamptz not null default now()
+);
+
+create index if not exists inbox_user_id on inbox (user_id, id);
+
+create index if not exists inbox_unread on inbox (user_id) where read_at is null;
+
+commit;
@@ -0,0 +1,3 @@
+alter table users
+    drop column if exists channels,
+    drop column if exists webhook_url;
@@ -0,0 +1,3 @@
+alter table users
+    add column if not exists channels    text[] not null default '{push}',
+    add column if not exists webhook_url text   not null default '';
@@ -0,0 +1,12 @@
+begin;
+
+drop index if exists notification_outbox_digest;
+
+delete from notification_outbox where kind = 2;
+
+alter table notification_outbox
+    drop column if exists channels;
+
+drop table if exists digest_settings;
+
+commit;
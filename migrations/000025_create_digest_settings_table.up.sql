@@ -0,0 +1,24 @@
+begin;
+
+-- times are minutes after the local midnight of the user, empty group_ids means all groups of the user
+create table if not exists digest_settings
+(
+    user_id     bigint primary key references users (id) on delete cascade,
+    daily       boolean     not null default false,
+    daily_time  smallint    not null default 420,
+    weekly      boolean     not null default false,
+    weekly_day  smallint    not null default 0,
+    weekly_time smallint    not null default 1080,
+    group_ids   bigint[]    not null default '{}',
+    email       boolean     not null default false,
+    updated_at  timestamptz not null default now()
+);
+
+-- null channels means channels chosen by the user
+alter table notification_outbox
+    add column if not exists channels text[];
+
+create unique index if not exists notification_outbox_digest
+    on notification_outbox (user_id, (data ->> 'digest'), send_at) where kind = 2;
+
+commit;
@@ -0,0 +1,14 @@
+begin;
+
+drop index if exists notification_outbox_digest;
+create unique index if not exists notification_outbox_digest
+    on notification_outbox (user_id, (data ->> 'digest'), send_at) where kind = 2;
+
+alter table users
+    drop column if exists quiet_enabled,
+    drop column if exists quiet_start,
+    drop column if exists quiet_end,
+    drop column if exists quiet_policy,
+    drop column if exists snooze_until;
+
+commit;
@@ -0,0 +1,16 @@
+begin;
+
+-- quiet hours are minutes after the local midnight of the user, the window can span the midnight
+alter table users
+    add column if not exists quiet_enabled boolean     not null default false,
+    add column if not exists quiet_start   smallint    not null default 1320,
+    add column if not exists quiet_end     smallint    not null default 420,
+    add column if not exists quiet_policy  text        not null default 'defer',
+    add column if not exists snooze_until  timestamptz;
+
+-- deferred digests get the new send time, so they are told apart by the date they cover
+drop index if exists notification_outbox_digest;
+create unique index if not exists notification_outbox_digest
+    on notification_outbox (user_id, (data ->> 'digest'), (data ->> 'date')) where kind = 2;
+
+commit;
@@ -0,0 +1 @@
+drop table if exists reminder_acks;
@@ -0,0 +1,10 @@
+-- acknowledged occurrence stops the remaining reminders of the member for it,
+-- reminders at the absolute time are acknowledged by that time
+create table if not exists reminder_acks
+(
+    user_id    bigint      not null references users (id) on delete cascade,
+    event_id   bigint      not null references events (id) on delete cascade,
+    occurrence timestamptz not null,
+    created_at timestamptz not null default now(),
+    primary key (user_id, event_id, occurrence)
+);
@@ -0,0 +1,21 @@
+begin;
+
+-- next time the reminder with the offset is sent for any occurrence of the series,
+-- reminders at the absolute time are found by notify_at and are not scheduled here
+create table if not exists reminder_schedule
+(
+    event_id      bigint      not null references events (id) on delete cascade,
+    notify_offset bigint      not null,
+    next_at       timestamptz not null,
+    primary key (event_id, notify_offset)
+);
+
+create index if not exists reminder_schedule_next_at on reminder_schedule (next_at);
+
+-- existing series are due at once, the sender computes their schedule on the first run
+insert into reminder_schedule (event_id, notify_offset, next_at)
+select id, -1, now()
+from events
+on conflict do nothing;
+
+commit;
@@ -0,0 +1,2 @@
+alter table user_group
+    drop column if exists role;
@@ -0,0 +1,13 @@
+begin;
+
+alter table user_group
+    add column if not exists role text not null default 'editor';
+
+-- creators kept their rights as owners, other members could edit events before
+update user_group ug
+set role = 'owner'
+from groups g
+where g.id = ug.group_id
+  and g.creator_id = ug.user_id;
+
+commit;
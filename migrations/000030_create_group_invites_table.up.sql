@@ -0,0 +1,21 @@
+begin;
+
+-- personal invites have user_id, invite links have the hash of the code instead,
+-- invites are deleted when accepted, declined or cancelled
+create table if not exists group_invites
+(
+    id         bigserial primary key,
+    group_id   bigint      not null references groups (id) on delete cascade,
+    inviter_id bigint      not null references users (id),
+    user_id    bigint references users (id),
+    code_hash  text unique,
+    role       text        not null default 'editor',
+    expires_at timestamptz not null,
+    created_at timestamptz not null default now(),
+    check ((user_id is null) <> (code_hash is null))
+);
+
+create unique index if not exists group_invites_group_id_user_id on group_invites (group_id, user_id) where user_id is not null;
+create index if not exists group_invites_user_id on group_invites (user_id) where user_id is not null;
+
+commit;
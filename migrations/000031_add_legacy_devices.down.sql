@@ -0,0 +1,10 @@
+begin;
+
+delete
+from devices
+where session_hash is null;
+
+alter table devices
+    alter column session_hash set not null;
+
+commit;
@@ -0,0 +1,13 @@
+begin;
+
+-- legacy push tokens are devices without a session, users.push_token is kept until clients stop using it
+alter table devices
+    alter column session_hash drop not null;
+
+insert into devices (user_id, push_token, platform)
+select id, push_token, ''
+from users
+where push_token <> ''
+  and push_token not in (select push_token from devices);
+
+commit;
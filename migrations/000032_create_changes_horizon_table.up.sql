@@ -0,0 +1,10 @@
+begin;
+
+-- the newest transaction whose changes were deleted, sync tokens up to it can't be served from the changes log
+create table if not exists changes_horizon
+(
+    id    boolean primary key default true check (id),
+    tx_id bigint not null
+);
+
+commit;